@@ -8,21 +8,44 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
+	_ "time/tzdata" // zonas horarias embebidas: la imagen alpine no trae tzdata
 
 	"github.com/andrew/orquestador-notificacion/internal/config"
+	"github.com/andrew/orquestador-notificacion/internal/contact"
+	"github.com/andrew/orquestador-notificacion/internal/digest"
 	"github.com/andrew/orquestador-notificacion/internal/handler"
+	"github.com/andrew/orquestador-notificacion/internal/health"
+	"github.com/andrew/orquestador-notificacion/internal/i18n"
+	"github.com/andrew/orquestador-notificacion/internal/idempotency"
 	kafkaPkg "github.com/andrew/orquestador-notificacion/internal/kafka"
 	"github.com/andrew/orquestador-notificacion/internal/logger"
+	"github.com/andrew/orquestador-notificacion/internal/metrics"
+	"github.com/andrew/orquestador-notificacion/internal/outbox"
+	"github.com/andrew/orquestador-notificacion/internal/preferences"
 	"github.com/andrew/orquestador-notificacion/internal/processor"
+	"github.com/andrew/orquestador-notificacion/internal/ratelimit"
+	"github.com/andrew/orquestador-notificacion/internal/routing"
 	"github.com/andrew/orquestador-notificacion/internal/service"
+	"github.com/andrew/orquestador-notificacion/internal/templates"
+	"github.com/andrew/orquestador-notificacion/internal/timer"
+	"github.com/andrew/orquestador-notificacion/internal/tracing"
 
 	kafka "github.com/segmentio/kafka-go"
 )
 
 const VERSION = "1.0.0"
 
+const (
+	// consumerWorkers es la cantidad de workers del consumer del topic principal
+	consumerWorkers = 4
+	// retryConsumerWorkers es la cantidad de workers de cada consumer de reintento; pasan
+	// la mayor parte del tiempo esperando la marca not-before de sus mensajes
+	retryConsumerWorkers = 2
+)
+
 var startTime = time.Now()
 
 type HealthResponse struct {
@@ -57,79 +80,103 @@ type HealthCheck struct {
 }
 
 type HealthResponseWithChecks struct {
-	Status        string       `json:"status"`
+	Status        string        `json:"status"`
 	Checks        []HealthCheck `json:"checks"`
-	Version       string       `json:"version"`
-	Uptime        string       `json:"uptime"`
-	UptimeSeconds int64        `json:"uptimeSeconds"`
+	Version       string        `json:"version"`
+	Uptime        string        `json:"uptime"`
+	UptimeSeconds int64         `json:"uptimeSeconds"`
+}
+
+// toHealthChecks convierte los resultados del registry de checks al formato de la respuesta
+func toHealthChecks(results []health.Result) []HealthCheck {
+	checks := make([]HealthCheck, 0, len(results))
+	for _, r := range results {
+		data := map[string]interface{}{
+			"from": r.Since.Format(time.RFC3339Nano),
+			"kind": r.Kind.String(),
+		}
+		for k, v := range r.Data {
+			data[k] = v
+		}
+		checks = append(checks, HealthCheck{Data: data, Name: r.Name, Status: r.Status})
+	}
+	return checks
+}
+
+// healthHandler agrega todos los checks; responde 503 si alguno está DOWN
+func healthHandler(checks *health.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		results := checks.Run(r.Context())
+		status, code := health.StatusUp, http.StatusOK
+		if !health.Healthy(results) {
+			status, code = health.StatusDown, http.StatusServiceUnavailable
+		}
+
+		uptime := time.Since(startTime)
+		response := HealthResponseWithChecks{
+			Status:        status,
+			Checks:        toHealthChecks(results),
+			Version:       VERSION,
+			Uptime:        formatUptime(uptime),
+			UptimeSeconds: int64(uptime.Seconds()),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// readyHandler refleja los checks de readiness (broker, producer, outbox, stores, lag)
+func readyHandler(checks *health.Registry) http.HandlerFunc {
+	return probeHandler(checks, health.Readiness, "READY", "NOT_READY")
 }
 
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	uptime := time.Since(startTime)
-	
-	checks := []HealthCheck{
-		{
-			Data: map[string]interface{}{
-				"from":   startTime.Format(time.RFC3339Nano),
-				"status": "READY",
-			},
-			Name:   "Readiness check",
-			Status: "UP",
-		},
-		{
-			Data: map[string]interface{}{
-				"from":   startTime.Format(time.RFC3339Nano),
-				"status": "ALIVE",
-			},
-			Name:   "Liveness check",
-			Status: "UP",
-		},
-	}
-	
-	response := HealthResponseWithChecks{
-		Status:        "UP",
-		Checks:        checks,
-		Version:       VERSION,
-		Uptime:        formatUptime(uptime),
-		UptimeSeconds: int64(uptime.Seconds()),
-	}
-	
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+// liveHandler refleja los checks de liveness (workers trabados o caídos)
+func liveHandler(checks *health.Registry) http.HandlerFunc {
+	return probeHandler(checks, health.Liveness, "LIVE", "NOT_LIVE")
 }
 
-func readyHandler(w http.ResponseWriter, r *http.Request) {
-	uptime := time.Since(startTime)
-	response := HealthResponse{
-		Status:        "READY",
-		Version:       VERSION,
-		Uptime:        formatUptime(uptime),
-		UptimeSeconds: int64(uptime.Seconds()),
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+func probeHandler(checks *health.Registry, kind health.Kind, up, down string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, code := up, http.StatusOK
+		if !health.Healthy(checks.Run(r.Context(), kind)) {
+			status, code = down, http.StatusServiceUnavailable
+		}
+
+		uptime := time.Since(startTime)
+		response := HealthResponse{
+			Status:        status,
+			Version:       VERSION,
+			Uptime:        formatUptime(uptime),
+			UptimeSeconds: int64(uptime.Seconds()),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(response)
+	}
 }
 
-func liveHandler(w http.ResponseWriter, r *http.Request) {
-	uptime := time.Since(startTime)
-	response := HealthResponse{
-		Status:        "LIVE",
-		Version:       VERSION,
-		Uptime:        formatUptime(uptime),
-		UptimeSeconds: int64(uptime.Seconds()),
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+// consumersHandler expone el lag por partición de cada consumer (endpoint de administración)
+func consumersHandler(consumers []*kafkaPkg.Consumer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reports := make([]kafkaPkg.LagReport, 0, len(consumers))
+		for _, c := range consumers {
+			reports = append(reports, c.Lag())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"consumers": reports})
+	}
 }
 
-func startHealthServer(port string) *http.Server {
+func startHealthServer(port string, checks *health.Registry, consumers []*kafkaPkg.Consumer) *http.Server {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/health", healthHandler)
-	mux.HandleFunc("/health/ready", readyHandler)
-	mux.HandleFunc("/health/live", liveHandler)
+	mux.HandleFunc("/health", healthHandler(checks))
+	mux.HandleFunc("/health/ready", readyHandler(checks))
+	mux.HandleFunc("/health/live", liveHandler(checks))
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/admin/consumers", consumersHandler(consumers))
 
 	server := &http.Server{
 		Addr:    ":" + port,
@@ -155,6 +202,16 @@ func main() {
 	// 2. Cargar configuración desde env
 	cfg := config.LoadFromEnv("[Config]", log)
 
+	// Trazas distribuidas: continúan el traceparent de los eventos entrantes
+	traceExporter, err := tracing.NewExporter(ctx, cfg.TracingExporter, cfg.TracingFile, cfg.OTLPEndpoint)
+	if err != nil {
+		log.Fatal("No se pudo inicializar el exporter de trazas", map[string]interface{}{
+			"error":    err.Error(),
+			"exporter": cfg.TracingExporter,
+		})
+	}
+	tracerProvider := tracing.Configure(cfg.ServiceName, cfg.TracingSampleRatio, traceExporter)
+
 	// 3. Verificar conexión a Kafka
 	log.Info("Verificando conectividad con Kafka...", map[string]interface{}{
 		"brokers": cfg.KafkaBrokers,
@@ -173,9 +230,142 @@ func main() {
 		"topic": producerTopic,
 	})
 
+	// Outbox: el servicio persiste las notificaciones en disco y un relay las publica,
+	// desacoplando las caídas del producer del avance del consumer
+	var notifier service.Producer = producer
+	var ob *outbox.Outbox
+	if cfg.OutboxEnabled {
+		var err error
+		ob, err = outbox.Open(cfg.OutboxFile, producer, logger.New("[Outbox]"))
+		if err != nil {
+			log.Fatal("No se pudo abrir el outbox", map[string]interface{}{
+				"error": err.Error(),
+				"file":  cfg.OutboxFile,
+			})
+		}
+		go ob.Run(ctx)
+		notifier = ob
+		log.Info("Outbox inicializado", map[string]interface{}{
+			"file":    cfg.OutboxFile,
+			"pending": ob.Pending(),
+		})
+	}
+
 	// 5. Servicios y Handlers
 	reg := handler.NewRegistry()
-	userSvc := service.NewUserService(producer, log)
+	prefStore, err := newPreferencesStore(cfg)
+	if err != nil {
+		log.Fatal("No se pudo inicializar el store de preferencias", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	timerStore, err := newTimerStore(cfg)
+	if err != nil {
+		log.Fatal("No se pudo inicializar el store de timers", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	channelStatus := service.NewChannelStatus(cfg.DisabledChannels)
+	svcOpts := []service.Option{
+		service.WithPreferences(prefStore, cfg.MandatoryTemplates),
+		service.WithFallback(cfg.FallbackChains, channelStatus),
+	}
+	if cfg.ContactValidation {
+		svcOpts = append(svcOpts, service.WithContactValidation(contact.NewNormalizer(cfg.DefaultPhoneCountry)))
+	}
+
+	// Supresión de notificaciones idénticas (ej: dos USER_LOGIN del mismo usuario en segundos)
+	dedupeWindows, err := service.ParseDedupeWindows(cfg.DedupeWindows)
+	if err != nil {
+		log.Fatal("Ventanas de deduplicación inválidas", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	if len(dedupeWindows) > 0 {
+		svcOpts = append(svcOpts, service.WithDedupeWindows(dedupeWindows))
+	}
+
+	// Resúmenes: alertas frecuentes (ej: login_alert) acumuladas en un único envío periódico
+	digestRules, err := digest.ParseRules(cfg.Digests)
+	if err != nil {
+		log.Fatal("Reglas de resumen inválidas", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	var digestStore digest.Store = digest.NewMemoryStore()
+	if len(digestRules) > 0 {
+		digestStore, err = digest.NewFileStore(cfg.DigestFile)
+		if err != nil {
+			log.Fatal("No se pudo abrir el store de resúmenes", map[string]interface{}{
+				"error": err.Error(),
+				"file":  cfg.DigestFile,
+			})
+		}
+		svcOpts = append(svcOpts, service.WithDigest(digestRules, digestStore, timerStore))
+		log.Info("Resúmenes habilitados", map[string]interface{}{
+			"templates": len(digestRules),
+			"pending":   digestStore.Len(),
+		})
+	}
+
+	// Templates locales: validan los datos de cada notificación y pueden incluir el contenido
+	if cfg.TemplatesDir != "" {
+		tmpl, err := templates.Load(cfg.TemplatesDir)
+		if err != nil {
+			log.Fatal("No se pudieron cargar los templates", map[string]interface{}{
+				"error": err.Error(),
+				"dir":   cfg.TemplatesDir,
+			})
+		}
+		svcOpts = append(svcOpts, service.WithTemplates(tmpl, cfg.TemplatesIncludeContent))
+		log.Info("Templates cargados", map[string]interface{}{
+			"dir":       cfg.TemplatesDir,
+			"templates": tmpl.Names(),
+		})
+	}
+
+	// Locales: variantes de template por idioma (welcome.es-CO) y catálogos de mensajes.
+	// Las variantes se eligen entre los templates locales: sin TEMPLATES_DIR la cadena de
+	// locales se ignoraría en silencio
+	var bundle *i18n.Bundle
+	if cfg.I18nDir != "" && cfg.TemplatesDir == "" {
+		log.Fatal("I18N_DIR requiere TEMPLATES_DIR para resolver las variantes de template por locale", map[string]interface{}{
+			"i18nDir": cfg.I18nDir,
+		})
+	}
+	if cfg.I18nDir != "" {
+		bundle, err = i18n.LoadBundle(cfg.I18nDir, cfg.DefaultLocale)
+		if err != nil {
+			log.Fatal("No se pudieron cargar los catálogos de mensajes", map[string]interface{}{
+				"error": err.Error(),
+				"dir":   cfg.I18nDir,
+			})
+		}
+		log.Info("Catálogos de mensajes cargados", map[string]interface{}{
+			"dir":     cfg.I18nDir,
+			"locales": bundle.Locales(),
+		})
+	}
+	svcOpts = append(svcOpts, service.WithLocales(bundle, cfg.DefaultLocale))
+
+	// Límites de tasa por destinatario, usuario y template (ej: evita abusar de OTP_REQUESTED)
+	limitRules, err := ratelimit.ParseRules(cfg.RateLimits)
+	if err != nil {
+		log.Fatal("Límites de tasa inválidos", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	limitStore, err := newRateLimitStore(cfg)
+	if err != nil {
+		log.Fatal("No se pudo inicializar el store de límites de tasa", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	if len(limitRules) > 0 {
+		svcOpts = append(svcOpts, service.WithRateLimit(ratelimit.NewLimiter(limitRules, limitStore)))
+	}
+	svcOpts = append(svcOpts, service.WithQuietHours(timerStore, cfg.QuietHoursTemplates))
+	userSvc := service.NewUserService(notifier, log, svcOpts...)
 
 	// Cada handler interpreta un tipo de evento y llama al servicio
 	reg.Register(handler.NewUserRegisteredHandler(userSvc, log))  // welcome
@@ -183,12 +373,78 @@ func main() {
 	reg.Register(handler.NewOtpRequestedHandler(userSvc, log))    // OTP
 	reg.Register(handler.NewUserLoginHandler(userSvc, log))       // login_alert
 	reg.Register(handler.NewUserVerifiedHandler(userSvc, log))    // verified_user
+	reg.Register(handler.NewUserPreferencesUpdatedHandler(userSvc, log))
 
 	log.Info("Handlers registrados exitosamente", map[string]interface{}{
-		"handlers": []string{"USER_REGISTERED", "PASSWORD_CHANGED", "OTP_REQUESTED", "USER_LOGIN", "USER_VERIFIED"},
+		"handlers": []string{"USER_REGISTERED", "PASSWORD_CHANGED", "OTP_REQUESTED", "USER_LOGIN", "USER_VERIFIED", "USER_PREFERENCES_UPDATED"},
+	})
+
+	// Reglas declarativas: un RuleHandler por cada tipo de evento del archivo de routing
+	if cfg.RoutingFile != "" {
+		rules, err := routing.Load(cfg.RoutingFile)
+		if err != nil {
+			log.Fatal("No se pudo cargar el archivo de routing", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		for _, eventType := range rules.EventTypes() {
+			reg.Register(handler.NewRuleHandler(eventType, rules.Events[eventType], userSvc, log))
+		}
+		log.Info("Reglas de routing registradas", map[string]interface{}{
+			"file":   cfg.RoutingFile,
+			"events": rules.EventTypes(),
+		})
+	}
+
+	// Políticas de reintento por tipo de evento (escalera de topics de reintento)
+	reg.SetDefaultRetryPolicy(handler.RetryPolicy{Delays: cfg.RetryDelays})
+	// Un OTP pierde vigencia rápido: 30s y 5m, luego DLQ
+	reg.SetRetryPolicy("OTP_REQUESTED", handler.RetryPolicy{Delays: handler.ExponentialBackoff(30*time.Second, 10, 2)})
+
+	// Store de idempotencia: evita reenviar notificaciones de eventos ya procesados
+	dedupeStore, err := newDedupeStore(cfg)
+	if err != nil {
+		log.Fatal("No se pudo inicializar el store de idempotencia", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	log.Info("Store de idempotencia inicializado", map[string]interface{}{
+		"store": cfg.IdempotencyStore,
+		"ttl":   cfg.IdempotencyTTL.String(),
 	})
 
-	proc := processor.NewProcessor(reg, log)
+	// El mismo store registra los pasos completados (canal + template) de cada evento.
+	// Los eventos programados (deliver_at / delay) se guardan en el store de timers.
+	proc := processor.NewProcessor(reg, log,
+		processor.WithDedupeStore(dedupeStore),
+		processor.WithProgressStore(dedupeStore),
+		processor.WithTimers(timerStore),
+	)
+
+	// Scheduler de timers: libera las notificaciones diferidas por horas de silencio y
+	// procesa los eventos programados al vencer. Los que no pueden ejecutarse se apartan
+	// en el archivo de descartados.
+	deadTimers, err := timer.OpenDeadLetter(cfg.TimerDeadLetterFile)
+	if err != nil {
+		log.Fatal("No se pudo abrir el archivo de timers descartados", map[string]interface{}{
+			"error": err.Error(),
+			"file":  cfg.TimerDeadLetterFile,
+		})
+	}
+	scheduler := timer.NewScheduler(timerStore, cfg.TimerPollInterval, logger.New("[Timers]"),
+		timer.WithMaxAttempts(cfg.TimerMaxAttempts),
+		timer.WithDeadLetter(deadTimers),
+	)
+	scheduler.Handle(service.TimerKindNotification, service.NotificationTimerHandler(userSvc))
+	scheduler.Handle(service.TimerKindDigest, service.DigestTimerHandler(userSvc))
+	scheduler.Handle(processor.TimerKindEvent, proc.FireTimer)
+	go scheduler.Run(ctx)
+	log.Info("Scheduler de timers iniciado", map[string]interface{}{
+		"store":     cfg.TimerStore,
+		"pending":   timerStore.Len(),
+		"discarded": deadTimers.Len(),
+		"interval":  cfg.TimerPollInterval.String(),
+	})
 
 	// 6. Consumer - escucha el topic de entrada (user-events)
 	rCfg := kafka.ReaderConfig{
@@ -198,16 +454,64 @@ func main() {
 		MinBytes: 10e3,
 		MaxBytes: 10e6,
 	}
-	consumer := kafkaPkg.NewConsumer(rCfg, proc, log)
+	// Producer para el topic de dead-letter (eventos que agotan sus intentos)
+	dlqProducer := kafkaPkg.NewProducer(cfg.KafkaBrokers, cfg.DLQTopic)
+	// Producers para la escalera de topics de reintento (user-events.retry.30s, ...)
+	retryTopics := kafkaPkg.NewRetryTopics(cfg.KafkaBrokers, cfg.KafkaTopic, reg.RetryDelays())
+	consumerOpts := []kafkaPkg.ConsumerOption{
+		kafkaPkg.WithDeadLetter(dlqProducer),
+		kafkaPkg.WithRetryTopics(retryTopics),
+		kafkaPkg.WithWorkerTimeout(cfg.WorkerTimeout),
+	}
+	// Solo el topic principal tiene límite de lag: en los de reintento el lag es esperado
+	mainOpts := append([]kafkaPkg.ConsumerOption{
+		kafkaPkg.WithLagThreshold(int64(cfg.LagThreshold), cfg.LagSustained),
+	}, consumerOpts...)
+	consumer := kafkaPkg.NewConsumer(rCfg, proc, log, mainOpts...)
+
+	// Un consumer por cada topic de reintento, en el orden de la escalera; esperan la marca
+	// not-before de cada mensaje
+	var retryConsumers []retryConsumer
+	consumers := []*kafkaPkg.Consumer{consumer}
+	for _, delay := range retryTopics.Delays() {
+		retryCfg := rCfg
+		retryCfg.Topic = kafkaPkg.RetryTopicName(cfg.KafkaTopic, delay)
+		retryCfg.GroupID = kafkaPkg.RetryGroupID(cfg.GroupID, delay)
+		rc := retryConsumer{
+			delay:    delay,
+			topic:    retryCfg.Topic,
+			consumer: kafkaPkg.NewConsumer(retryCfg, proc, log, consumerOpts...),
+		}
+		retryConsumers = append(retryConsumers, rc)
+		consumers = append(consumers, rc.consumer)
+	}
 
 	log.Info("Consumer de Kafka configurado", map[string]interface{}{
-		"topic":   cfg.KafkaTopic,
-		"groupID": cfg.GroupID,
+		"topic":       cfg.KafkaTopic,
+		"groupID":     cfg.GroupID,
+		"dlqTopic":    cfg.DLQTopic,
+		"retryTopics": retryTopics.Topics(),
 	})
 
-	// 7. Iniciar servidor HTTP para health checks
+	// 7. Health checks: cada componente registra los suyos
+	checks := health.NewRegistry()
+	checks.Register("kafka", health.Readiness, kafkaPkg.BrokerCheck(cfg.KafkaBrokers))
+	checks.Register("producer", health.Readiness, producer.Check)
+	if ob != nil {
+		checks.Register("outbox", health.Readiness, ob.Check)
+	}
+	if dirs := storeDirs(cfg, ob != nil, len(digestRules) > 0); len(dirs) > 0 {
+		checks.Register("stores", health.Readiness, health.WritableDirs(dirs...))
+	}
+	checks.Register("consumer-lag", health.Readiness, consumer.LagCheck)
+	checks.Register("consumer:"+cfg.KafkaTopic, health.Liveness, consumer.Liveness)
+	for _, rc := range retryConsumers {
+		checks.Register("consumer:"+rc.topic, health.Liveness, rc.consumer.Liveness)
+	}
+
+	// Iniciar servidor HTTP para health checks y métricas
 	healthPort := getEnv("HEALTH_PORT", "8080")
-	healthServer := startHealthServer(healthPort)
+	healthServer := startHealthServer(healthPort, checks, consumers)
 	log.Info("Servidor de health checks iniciado", map[string]interface{}{
 		"port": healthPort,
 	})
@@ -221,20 +525,11 @@ func main() {
 		}
 	}()
 
-	// 8. Iniciar consumer (después del health server)
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Error("Panic recuperado en consumer", map[string]interface{}{
-					"panic": fmt.Sprintf("%v", r),
-				})
-			}
-		}()
-		log.Info("Iniciando consumer con workers", map[string]interface{}{
-			"workers": 4,
-		})
-		consumer.Start(ctx, 4) // 4 workers en paralelo
-	}()
+	// 8. Iniciar consumers (después del health server): el principal y los de reintento
+	startConsumer(ctx, log, consumer, cfg.KafkaTopic, consumerWorkers)
+	for _, rc := range retryConsumers {
+		startConsumer(ctx, log, rc.consumer, rc.topic, retryConsumerWorkers)
+	}
 
 	// 8. Esperar señal para apagado
 	sig := make(chan os.Signal, 1)
@@ -248,10 +543,54 @@ func main() {
 	log.Info("Iniciando cierre ordenado...", nil)
 	time.Sleep(3 * time.Second)
 	_ = consumer.Close()
+	for _, rc := range retryConsumers {
+		_ = rc.consumer.Close()
+	}
+	_ = retryTopics.Close()
+	if ob != nil {
+		_ = ob.Close()
+	}
 	_ = producer.Close()
+	_ = dlqProducer.Close()
+	_ = dedupeStore.Close()
+	_ = prefStore.Close()
+	_ = timerStore.Close()
+	_ = deadTimers.Close()
+	_ = limitStore.Close()
+	_ = digestStore.Close()
+	// Exporta los spans pendientes del último lote
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	_ = tracerProvider.Shutdown(shutdownCtx)
+	shutdownCancel()
 	log.Info("Orquestador finalizado correctamente", nil)
 }
 
+// retryConsumer es el consumer de un escalón de la escalera de reintentos
+type retryConsumer struct {
+	delay    time.Duration
+	topic    string
+	consumer *kafkaPkg.Consumer
+}
+
+// startConsumer lanza los workers del consumer; un panic al iniciar se registra sin tumbar el proceso
+func startConsumer(ctx context.Context, log *logger.Logger, c *kafkaPkg.Consumer, topic string, workers int) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error("Panic recuperado en consumer", map[string]interface{}{
+					"topic": topic,
+					"panic": fmt.Sprintf("%v", r),
+				})
+			}
+		}()
+		log.Info("Iniciando consumer con workers", map[string]interface{}{
+			"topic":   topic,
+			"workers": workers,
+		})
+		c.Start(ctx, workers)
+	}()
+}
+
 // Verifica que Kafka esté disponible
 func checkKafkaConnectivity(brokers []string) error {
 	if len(brokers) == 0 {
@@ -270,6 +609,73 @@ func checkKafkaConnectivity(brokers []string) error {
 	return nil
 }
 
+// newDedupeStore crea el store de idempotencia según la configuración
+func newDedupeStore(cfg config.Config) (idempotency.Store, error) {
+	if cfg.IdempotencyStore == "file" {
+		return idempotency.NewFileStore(cfg.IdempotencyFile, cfg.IdempotencyTTL)
+	}
+	return idempotency.NewMemoryStore(cfg.IdempotencyCapacity, cfg.IdempotencyTTL), nil
+}
+
+// newPreferencesStore crea el store de preferencias según la configuración
+func newPreferencesStore(cfg config.Config) (preferences.Store, error) {
+	if cfg.PreferencesStore == "memory" {
+		return preferences.NewMemoryStore(), nil
+	}
+	return preferences.NewFileStore(cfg.PreferencesFile)
+}
+
+// newTimerStore crea el store de timers según la configuración
+func newTimerStore(cfg config.Config) (timer.Store, error) {
+	if cfg.TimerStore == "memory" {
+		return timer.NewMemoryStore(), nil
+	}
+	return timer.NewFileStore(cfg.TimerFile)
+}
+
+// newRateLimitStore crea el store de límites de tasa según la configuración
+func newRateLimitStore(cfg config.Config) (ratelimit.Store, error) {
+	if cfg.RateLimitStore == "memory" {
+		return ratelimit.NewMemoryStore(), nil
+	}
+	return ratelimit.NewFileStore(cfg.RateLimitFile)
+}
+
+// storeDirs retorna los directorios de los stores en archivo habilitados
+func storeDirs(cfg config.Config, outboxEnabled, digestEnabled bool) []string {
+	var files []string
+	if cfg.IdempotencyStore == "file" {
+		files = append(files, cfg.IdempotencyFile)
+	}
+	if cfg.PreferencesStore == "file" {
+		files = append(files, cfg.PreferencesFile)
+	}
+	if cfg.TimerStore == "file" {
+		files = append(files, cfg.TimerFile)
+	}
+	files = append(files, cfg.TimerDeadLetterFile)
+	if cfg.RateLimitStore == "file" {
+		files = append(files, cfg.RateLimitFile)
+	}
+	if digestEnabled {
+		files = append(files, cfg.DigestFile)
+	}
+	if outboxEnabled {
+		files = append(files, cfg.OutboxFile)
+	}
+
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, f := range files {
+		dir := filepath.Dir(f)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
 // Helper para valores por defecto
 func getEnv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
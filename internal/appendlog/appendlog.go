@@ -0,0 +1,186 @@
+// Package appendlog implementa el log append-only en disco (un registro JSON por línea) que
+// respalda a los stores locales: outbox, timers, rate limit, idempotencia y resúmenes.
+//
+// Cada store mantiene su estado en memoria, registra cada cambio con Append y, cuando el
+// log acumula demasiados registros obsoletos (ShouldCompact), lo reescribe con Rewrite.
+// Log no es seguro para uso concurrente: el store lo usa bajo su propio lock.
+package appendlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// compactMinRecords evita compactar archivos pequeños
+const compactMinRecords = 1000
+
+// Log es un archivo de registros R, uno por línea
+type Log[R any] struct {
+	path    string
+	name    string
+	file    *os.File
+	records int
+}
+
+// Open abre (o crea) el log en path y entrega a apply cada registro guardado, en orden.
+// name identifica al store en los mensajes de error (ej: "timers").
+func Open[R any](path, name string, apply func(R)) (*Log[R], error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("crear directorio de %s: %w", name, err)
+	}
+	l := &Log[R]{path: path, name: name}
+	if err := l.load(apply); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("abrir %s: %w", name, err)
+	}
+	l.file = f
+	return l, nil
+}
+
+// load entrega los registros guardados a apply. Una caída durante Append puede dejar la
+// última línea incompleta: se recorta el archivo hasta la última línea completa (o se le
+// agrega el salto de línea si el registro llegó entero), para que el próximo Append no se
+// escriba pegado a ella y se pierda.
+func (l *Log[R]) load(apply func(R)) error {
+	f, err := os.OpenFile(l.path, os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("abrir %s: %w", l.name, err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReaderSize(f, 64*1024)
+	var complete int64 // bytes hasta el final de la última línea completa
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("leer %s: %w", l.name, err)
+		}
+		if len(line) == 0 {
+			break
+		}
+		var rec R
+		ok := json.Unmarshal(line, &rec) == nil
+		if ok {
+			// Una línea completa que no decodifica se ignora; la cola incompleta se repara abajo
+			apply(rec)
+			l.records++
+		}
+		if err == io.EOF {
+			if ok {
+				_, err = f.WriteAt([]byte{'\n'}, complete+int64(len(line)))
+			} else {
+				err = f.Truncate(complete)
+			}
+			if err == nil {
+				err = f.Sync()
+			}
+			if err != nil {
+				return fmt.Errorf("reparar %s: %w", l.name, err)
+			}
+			break
+		}
+		complete += int64(len(line))
+	}
+	return nil
+}
+
+// Append escribe los registros y sincroniza el archivo una sola vez; retorna solo cuando
+// quedaron persistidos en disco
+func (l *Log[R]) Append(recs ...R) error {
+	if l.file == nil {
+		return fmt.Errorf("%s cerrado", l.name)
+	}
+	var buf []byte
+	for _, rec := range recs {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+	if _, err := l.file.Write(buf); err != nil {
+		return fmt.Errorf("escribir %s: %w", l.name, err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("sincronizar %s: %w", l.name, err)
+	}
+	l.records += len(recs)
+	return nil
+}
+
+// ShouldCompact indica si el log tiene suficientes registros obsoletos para reescribirlo,
+// dado que live registros bastan para reconstruir el estado actual
+func (l *Log[R]) ShouldCompact(live int) bool {
+	return l.records > compactMinRecords && l.records > 2*live
+}
+
+// Rewrite reemplaza el log por recs (el estado actual del store). El archivo nuevo se
+// escribe aparte y se renombra sobre el anterior; si algo falla, el log sigue escribiendo
+// en el archivo anterior.
+func (l *Log[R]) Rewrite(recs []R) error {
+	if l.file == nil {
+		return fmt.Errorf("%s cerrado", l.name)
+	}
+	tmpPath := l.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_APPEND|os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("compactar %s: %w", l.name, err)
+	}
+	discard := func(err error) error {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("compactar %s: %w", l.name, err)
+	}
+
+	w := bufio.NewWriter(tmp)
+	for _, rec := range recs {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return discard(err)
+		}
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		return discard(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return discard(err)
+	}
+	// El descriptor del temporal sigue siendo válido tras el rename: pasa a ser el log
+	if err := os.Rename(tmpPath, l.path); err != nil {
+		return discard(err)
+	}
+
+	old := l.file
+	l.file = tmp
+	l.records = len(recs)
+	if err := old.Close(); err != nil {
+		return fmt.Errorf("cerrar %s compactado: %w", l.name, err)
+	}
+	return nil
+}
+
+// Closed indica si el log ya fue cerrado
+func (l *Log[R]) Closed() bool {
+	return l.file == nil
+}
+
+func (l *Log[R]) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,170 @@
+package appendlog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type entry struct {
+	Key   string `json:"key"`
+	Value int    `json:"value"`
+}
+
+func openCollect(t *testing.T, path string) (*Log[entry], []entry) {
+	t.Helper()
+	var got []entry
+	l, err := Open(path, "test", func(e entry) { got = append(got, e) })
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return l, got
+}
+
+func TestAppendAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "store.log")
+	l, got := openCollect(t, path)
+	if len(got) != 0 {
+		t.Fatalf("log nuevo con %d registros", len(got))
+	}
+	if err := l.Append(entry{"a", 1}, entry{"b", 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Append(entry{"a", 3}); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	// Una línea truncada por una caída se descarta sin perder las anteriores, y el próximo
+	// registro no queda pegado a ella
+	appendRaw(t, path, `{"key":"c","val`)
+	l, _ = openCollect(t, path)
+	if err := l.Append(entry{"d", 4}); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	l, got = openCollect(t, path)
+	l.Close()
+	assertEntries(t, got, []entry{{"a", 1}, {"b", 2}, {"a", 3}, {"d", 4}})
+
+	// Un registro completo al que solo le faltó el salto de línea se conserva
+	appendRaw(t, path, `{"key":"e","value":5}`)
+	l, _ = openCollect(t, path)
+	if err := l.Append(entry{"f", 6}); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	l, got = openCollect(t, path)
+	defer l.Close()
+	assertEntries(t, got, []entry{{"a", 1}, {"b", 2}, {"a", 3}, {"d", 4}, {"e", 5}, {"f", 6}})
+}
+
+func appendRaw(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertEntries(t *testing.T, got, want []entry) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("registros = %v, se esperaba %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("registro %d = %v, se esperaba %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.log")
+	l, _ := openCollect(t, path)
+	for i := 0; i < 10; i++ {
+		if err := l.Append(entry{"a", i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Rewrite([]entry{{"a", 9}}); err != nil {
+		t.Fatal(err)
+	}
+	// El log compactado sigue aceptando registros
+	if err := l.Append(entry{"b", 1}); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	_, got := openCollect(t, path)
+	if len(got) != 2 || got[0] != (entry{"a", 9}) || got[1] != (entry{"b", 1}) {
+		t.Fatalf("tras compactar = %v", got)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("quedó el archivo temporal: %v", err)
+	}
+}
+
+func TestRewriteFailureKeepsLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.log")
+	l, _ := openCollect(t, path)
+	defer l.Close()
+	if err := l.Append(entry{"a", 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Un directorio no vacío con el nombre del temporal hace fallar la compactación
+	if err := os.MkdirAll(filepath.Join(path+".tmp", "x"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Rewrite([]entry{{"a", 1}}); err == nil {
+		t.Fatal("se esperaba un error al compactar")
+	}
+	if l.Closed() {
+		t.Fatal("el log quedó cerrado tras fallar la compactación")
+	}
+	if err := l.Append(entry{"b", 2}); err != nil {
+		t.Fatalf("Append tras compactación fallida: %v", err)
+	}
+	l.Close()
+
+	_, got := openCollect(t, path)
+	if len(got) != 2 {
+		t.Fatalf("registros = %v, se esperaban 2", got)
+	}
+}
+
+func TestShouldCompact(t *testing.T) {
+	l := &Log[entry]{records: compactMinRecords + 1}
+	tests := []struct {
+		live int
+		want bool
+	}{
+		{0, true},
+		{compactMinRecords / 2, true},
+		{compactMinRecords, false},
+	}
+	for _, tt := range tests {
+		if got := l.ShouldCompact(tt.live); got != tt.want {
+			t.Errorf("ShouldCompact(%d) = %v, se esperaba %v", tt.live, got, tt.want)
+		}
+	}
+	if (&Log[entry]{records: 10}).ShouldCompact(0) {
+		t.Error("no se deben compactar logs pequeños")
+	}
+}
+
+func TestClosed(t *testing.T) {
+	l, _ := openCollect(t, filepath.Join(t.TempDir(), "store.log"))
+	l.Close()
+	if err := l.Append(entry{"a", 1}); err == nil {
+		t.Error("Append sobre un log cerrado no falló")
+	}
+	if err := l.Close(); err != nil {
+		t.Errorf("segundo Close: %v", err)
+	}
+}
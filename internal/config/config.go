@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/andrew/orquestador-notificacion/internal/logger"
 )
@@ -11,6 +13,92 @@ type Config struct {
 	KafkaBrokers []string
 	KafkaTopic   string
 	GroupID      string
+	DLQTopic     string
+	RetryDelays  []time.Duration
+
+	// Idempotencia: "file" (archivo embebido, por defecto) o "memory" (LRU+TTL, solo para
+	// desarrollo: no sobrevive a una caída antes del commit)
+	IdempotencyStore    string
+	IdempotencyFile     string
+	IdempotencyTTL      time.Duration
+	IdempotencyCapacity int
+
+	// Archivo de reglas de routing (YAML/JSON); vacío deshabilita el routing declarativo
+	RoutingFile string
+
+	// Preferencias de usuario: "memory" o "file". Los templates de MANDATORY_TEMPLATES
+	// (ej: "password_recovery") ignoran el opt-out; por defecto ninguno
+	PreferencesStore   string
+	PreferencesFile    string
+	MandatoryTemplates []string
+
+	// Timers durables (notificaciones diferidas): "memory" o "file"
+	TimerStore        string
+	TimerFile         string
+	TimerPollInterval time.Duration
+	// Ejecuciones fallidas tras las que un timer se descarta al archivo de descartados
+	TimerMaxAttempts    int
+	TimerDeadLetterFile string
+	// Templates que se difieren durante las horas de silencio del usuario
+	QuietHoursTemplates []string
+
+	// Cadenas de canales alternativos por template (ej: login_alert: SMS → WHATSAPP → EMAIL)
+	// y canales fuera de servicio
+	FallbackChains   map[string][]string
+	DisabledChannels []string
+
+	// Validación de destinatarios (email, E.164), deshabilitada por defecto:
+	// CONTACT_VALIDATION=true la habilita. DefaultPhoneCountry (ISO 3166) se usa para los
+	// teléfonos sin código de país
+	ContactValidation   bool
+	DefaultPhoneCountry string
+
+	// Límites de envío "template.scope=N/duración;..." (ver ratelimit.ParseRules), sin
+	// límites por defecto (ej: RATE_LIMITS="password_recovery.recipient=3/1h"), y store de
+	// sus buckets: "memory" o "file"
+	RateLimits     string
+	RateLimitStore string
+	RateLimitFile  string
+
+	// Ventanas de supresión de notificaciones idénticas por template
+	// "template=ventana[:campo,campo];..." (ver service.ParseDedupeWindows), sin ventanas
+	// por defecto (ej: DEDUPE_WINDOWS="login_alert=30s")
+	DedupeWindows string
+
+	// Resúmenes "template=digest_template:max_items:ventana;..." (ver digest.ParseRules)
+	// y archivo donde se persisten los resúmenes en construcción
+	Digests    string
+	DigestFile string
+
+	// Directorio de templates versionados (vacío deshabilita el renderizado local) y si el
+	// contenido renderizado se incluye en el NotificationEvent
+	TemplatesDir            string
+	TemplatesIncludeContent bool
+
+	// Locale por defecto de las notificaciones y directorio de catálogos de mensajes
+	// <locale>.yaml (vacío deshabilita los catálogos; requiere TemplatesDir)
+	DefaultLocale string
+	I18nDir       string
+
+	// Readiness por lag: el consumer deja de estar listo si el lag total supera LagThreshold
+	// mensajes durante LagSustained (0 deshabilita el límite)
+	LagThreshold int
+	LagSustained time.Duration
+	// Tiempo máximo de un worker con un mensaje antes de que liveness lo considere trabado
+	WorkerTimeout time.Duration
+
+	// Trazas: exporter "none", "stdout", "file" u "otlp" (OTLP/HTTP en OTLPEndpoint) y
+	// fracción de trazas nuevas muestreadas
+	TracingExporter    string
+	TracingFile        string
+	TracingSampleRatio float64
+	OTLPEndpoint       string
+	ServiceName        string
+
+	// Outbox local: las notificaciones se persisten antes de publicarse en Kafka.
+	// Deshabilitado por defecto; OUTBOX_ENABLED=true lo habilita
+	OutboxEnabled bool
+	OutboxFile    string
 }
 
 // LoadFromEnv carga configuración desde variables de entorno y usa logger estructurado
@@ -39,17 +127,248 @@ func LoadFromEnv(loggerName string, log *logger.Logger) Config {
 		})
 	}
 
+	dlqTopic := os.Getenv("KAFKA_DLQ_TOPIC")
+	if dlqTopic == "" {
+		dlqTopic = topic + ".dlq"
+		log.Warn("KAFKA_DLQ_TOPIC no definido, usando valor por defecto", map[string]interface{}{
+			"default": dlqTopic,
+		})
+	}
+
+	idempotencyStore := os.Getenv("IDEMPOTENCY_STORE")
+	if idempotencyStore != "memory" {
+		idempotencyStore = "file"
+	} else {
+		log.Warn("IDEMPOTENCY_STORE=memory: los eventos reentregados tras un reinicio se volverán a notificar", nil)
+	}
+
+	preferencesStore := os.Getenv("PREFERENCES_STORE")
+	if preferencesStore != "memory" {
+		preferencesStore = "file"
+	}
+
+	timerStore := os.Getenv("TIMER_STORE")
+	if timerStore != "memory" {
+		timerStore = "file"
+	}
+
+	rateLimitStore := os.Getenv("RATE_LIMIT_STORE")
+	if rateLimitStore != "memory" {
+		rateLimitStore = "file"
+	}
+
 	config := Config{
-		KafkaBrokers: strings.Split(brokers, ","),
-		KafkaTopic:   topic,
-		GroupID:      groupID,
+		KafkaBrokers:            strings.Split(brokers, ","),
+		KafkaTopic:              topic,
+		GroupID:                 groupID,
+		DLQTopic:                dlqTopic,
+		RetryDelays:             getDurationListEnv("KAFKA_RETRY_DELAYS", []time.Duration{30 * time.Second, 5 * time.Minute, time.Hour}, log),
+		IdempotencyStore:        idempotencyStore,
+		IdempotencyFile:         getEnv("IDEMPOTENCY_FILE", "data/idempotency.log"),
+		IdempotencyTTL:          getDurationEnv("IDEMPOTENCY_TTL", 24*time.Hour, log),
+		IdempotencyCapacity:     getIntEnv("IDEMPOTENCY_CAPACITY", 100000, log),
+		RoutingFile:             os.Getenv("ROUTING_FILE"),
+		PreferencesStore:        preferencesStore,
+		PreferencesFile:         getEnv("PREFERENCES_FILE", "data/preferences.json"),
+		MandatoryTemplates:      getListEnv("MANDATORY_TEMPLATES", nil),
+		TimerStore:              timerStore,
+		TimerFile:               getEnv("TIMER_FILE", "data/timers.log"),
+		TimerPollInterval:       getDurationEnv("TIMER_POLL_INTERVAL", 5*time.Second, log),
+		TimerMaxAttempts:        getIntEnv("TIMER_MAX_ATTEMPTS", 10, log),
+		TimerDeadLetterFile:     getEnv("TIMER_DEAD_LETTER_FILE", "data/timers.dead.log"),
+		QuietHoursTemplates:     getListEnv("QUIET_HOURS_TEMPLATES", []string{"welcome", "account_verified"}),
+		FallbackChains:          getChainsEnv("FALLBACK_CHAINS", log),
+		DisabledChannels:        getListEnv("DISABLED_CHANNELS", nil),
+		DefaultPhoneCountry:     getEnv("DEFAULT_PHONE_COUNTRY", "CO"),
+		ContactValidation:       os.Getenv("CONTACT_VALIDATION") == "true",
+		RateLimits:              os.Getenv("RATE_LIMITS"),
+		RateLimitStore:          rateLimitStore,
+		RateLimitFile:           getEnv("RATE_LIMIT_FILE", "data/ratelimit.log"),
+		DedupeWindows:           os.Getenv("DEDUPE_WINDOWS"),
+		Digests:                 os.Getenv("DIGESTS"),
+		DigestFile:              getEnv("DIGEST_FILE", "data/digests.log"),
+		TemplatesDir:            os.Getenv("TEMPLATES_DIR"),
+		TemplatesIncludeContent: os.Getenv("TEMPLATES_INCLUDE_CONTENT") != "false",
+		DefaultLocale:           getEnv("DEFAULT_LOCALE", "es"),
+		I18nDir:                 os.Getenv("I18N_DIR"),
+		LagThreshold:            getIntEnv("LAG_THRESHOLD", 10000, log),
+		LagSustained:            getDurationEnv("LAG_SUSTAINED", 5*time.Minute, log),
+		WorkerTimeout:           getDurationEnv("WORKER_TIMEOUT", 5*time.Minute, log),
+		TracingExporter:         getEnv("TRACING_EXPORTER", "none"),
+		TracingFile:             getEnv("TRACING_FILE", "data/traces.log"),
+		TracingSampleRatio:      getFloatEnv("TRACING_SAMPLE_RATIO", 1, log),
+		OTLPEndpoint:            getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318"),
+		ServiceName:             getEnv("OTEL_SERVICE_NAME", "orquestador-notificacion"),
+		OutboxEnabled:           os.Getenv("OUTBOX_ENABLED") == "true",
+		OutboxFile:              getEnv("OUTBOX_FILE", "data/outbox.log"),
 	}
 
 	log.Info("Configuración de Kafka cargada exitosamente", map[string]interface{}{
-		"brokers": config.KafkaBrokers,
-		"topic":   config.KafkaTopic,
-		"groupID": config.GroupID,
+		"brokers":           config.KafkaBrokers,
+		"topic":             config.KafkaTopic,
+		"groupID":           config.GroupID,
+		"dlqTopic":          config.DLQTopic,
+		"retryDelays":       formatDurations(config.RetryDelays),
+		"idempotencyStore":  config.IdempotencyStore,
+		"idempotencyTTL":    config.IdempotencyTTL.String(),
+		"outboxEnabled":     config.OutboxEnabled,
+		"routingFile":       config.RoutingFile,
+		"preferencesStore":  config.PreferencesStore,
+		"timerStore":        config.TimerStore,
+		"fallbackChains":    config.FallbackChains,
+		"disabledChannels":  config.DisabledChannels,
+		"contactValidation": config.ContactValidation,
+		"rateLimits":        config.RateLimits,
+		"rateLimitStore":    config.RateLimitStore,
+		"dedupeWindows":     config.DedupeWindows,
+		"digests":           config.Digests,
+		"templatesDir":      config.TemplatesDir,
+		"defaultLocale":     config.DefaultLocale,
+		"i18nDir":           config.I18nDir,
+		"lagThreshold":      config.LagThreshold,
+		"lagSustained":      config.LagSustained.String(),
+		"workerTimeout":     config.WorkerTimeout.String(),
+		"tracingExporter":   config.TracingExporter,
 	})
 
 	return config
 }
+
+// getEnv retorna el valor de la variable o el valor por defecto
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// getListEnv lee una lista separada por comas (ej: "welcome,account_verified")
+func getListEnv(key string, fallback []string) []string {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	var out []string
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// getChainsEnv lee cadenas de canales por template con el formato
+// "template=CANAL>CANAL>CANAL;template=CANAL>CANAL" (ej: "login_alert=SMS>WHATSAPP>EMAIL")
+func getChainsEnv(key string, log *logger.Logger) map[string][]string {
+	chains := make(map[string][]string)
+	raw := os.Getenv(key)
+	if raw == "" {
+		return chains
+	}
+	for _, entry := range strings.Split(raw, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		template, chain, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(template) == "" {
+			log.Warn("Cadena de canales inválida, se ignora", map[string]interface{}{
+				"key":   key,
+				"value": entry,
+			})
+			continue
+		}
+		var channels []string
+		for _, ch := range strings.Split(chain, ">") {
+			if ch = strings.TrimSpace(ch); ch != "" {
+				channels = append(channels, strings.ToUpper(ch))
+			}
+		}
+		chains[strings.TrimSpace(template)] = channels
+	}
+	return chains
+}
+
+// getIntEnv lee un entero de una variable de entorno, con valor por defecto
+func getIntEnv(key string, fallback int, log *logger.Logger) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		log.Warn("Valor entero inválido, usando valor por defecto", map[string]interface{}{
+			"key":     key,
+			"value":   raw,
+			"default": fallback,
+		})
+		return fallback
+	}
+	return v
+}
+
+// getFloatEnv lee un número decimal de una variable de entorno, con valor por defecto
+func getFloatEnv(key string, fallback float64, log *logger.Logger) float64 {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		log.Warn("Valor decimal inválido, usando valor por defecto", map[string]interface{}{
+			"key":     key,
+			"value":   raw,
+			"default": fallback,
+		})
+		return fallback
+	}
+	return v
+}
+
+// getDurationEnv lee una duración positiva (ej: "5s", "1m") de una variable de entorno
+func getDurationEnv(key string, fallback time.Duration, log *logger.Logger) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	v, err := time.ParseDuration(raw)
+	if err != nil || v <= 0 {
+		log.Warn("Duración inválida, usando valor por defecto", map[string]interface{}{
+			"key":     key,
+			"value":   raw,
+			"default": fallback.String(),
+		})
+		return fallback
+	}
+	return v
+}
+
+// getDurationListEnv lee una lista de duraciones separadas por comas (ej: "30s,5m,1h")
+func getDurationListEnv(key string, fallback []time.Duration, log *logger.Logger) []time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	var out []time.Duration
+	for _, part := range strings.Split(raw, ",") {
+		v, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil || v <= 0 {
+			log.Warn("Lista de duraciones inválida, usando valor por defecto", map[string]interface{}{
+				"key":     key,
+				"value":   raw,
+				"default": formatDurations(fallback),
+			})
+			return fallback
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+func formatDurations(ds []time.Duration) []string {
+	out := make([]string, len(ds))
+	for i, d := range ds {
+		out[i] = d.String()
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+// Package contact valida y normaliza los destinatarios de las notificaciones:
+// emails (sintaxis tipo RFC 5322, dominio en minúsculas) y teléfonos en formato E.164.
+package contact
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InvalidError indica un destinatario inválido. Es un error permanente: reintentar
+// no lo corrige, por lo que el evento debe ir al DLQ.
+type InvalidError struct {
+	Channel string
+	Value   string
+	Reason  string
+}
+
+func (e *InvalidError) Error() string {
+	return fmt.Sprintf("destinatario %s inválido %q: %s", e.Channel, e.Value, e.Reason)
+}
+
+// Permanent marca el error como no reintentable
+func (e *InvalidError) Permanent() bool {
+	return true
+}
+
+// Normalizer normaliza el destinatario según el canal
+type Normalizer struct {
+	defaultCountry string
+}
+
+// NewNormalizer crea un Normalizer; defaultCountry (ISO 3166, ej: "CO") se usa para los
+// teléfonos sin código de país
+func NewNormalizer(defaultCountry string) *Normalizer {
+	return &Normalizer{defaultCountry: strings.ToUpper(strings.TrimSpace(defaultCountry))}
+}
+
+// Normalize valida el destinatario del canal y retorna su forma normalizada.
+// Los canales sin formato conocido (ej: PUSH) solo exigen un valor no vacío.
+func (n *Normalizer) Normalize(channel, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", &InvalidError{Channel: channel, Value: value, Reason: "vacío"}
+	}
+
+	var (
+		out    string
+		reason string
+	)
+	switch channel {
+	case "EMAIL":
+		out, reason = normalizeEmail(value)
+	case "SMS", "WHATSAPP":
+		out, reason = normalizePhone(value, n.defaultCountry)
+	default:
+		return value, nil
+	}
+	if reason != "" {
+		return "", &InvalidError{Channel: channel, Value: value, Reason: reason}
+	}
+	return out, nil
+}
+
+// NormalizeEmail valida la sintaxis del email y pasa su dominio a minúsculas
+func NormalizeEmail(raw string) (string, error) {
+	out, reason := normalizeEmail(strings.TrimSpace(raw))
+	if reason != "" {
+		return "", &InvalidError{Channel: "EMAIL", Value: raw, Reason: reason}
+	}
+	return out, nil
+}
+
+// NormalizePhone convierte el teléfono a E.164 (ej: "+573001234567")
+func NormalizePhone(raw, defaultCountry string) (string, error) {
+	out, reason := normalizePhone(strings.TrimSpace(raw), strings.ToUpper(defaultCountry))
+	if reason != "" {
+		return "", &InvalidError{Channel: "SMS", Value: raw, Reason: reason}
+	}
+	return out, nil
+}
@@ -0,0 +1,107 @@
+package contact
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		country string
+		want    string // vacío = inválido
+	}{
+		{"E.164", "+57 300 123 4567", "CO", "+573001234567"},
+		{"E.164 con separadores", "+1 (305) 555-1234", "CO", "+13055551234"},
+		{"prefijo internacional 00", "0057 3001234567", "CO", "+573001234567"},
+		{"nacional", "300-123-4567", "CO", "+573001234567"},
+		{"nacional con prefijo troncal", "0991234567", "EC", "+593991234567"},
+		{"nacional con código de país", "573001234567", "CO", "+573001234567"},
+		{"nacional con código de país y espacios", "57 300 123 4567", "CO", "+573001234567"},
+		{"NANP con 1 inicial", "1 305 555 1234", "US", "+13055551234"},
+		{"nacional que empieza como el código", "5512345678", "MX", "+525512345678"},
+		{"letras", "300-ABC-4567", "CO", ""},
+		{"nacional corto", "300123", "CO", ""},
+		{"nacional largo", "30012345678901", "CO", ""},
+		{"país por defecto desconocido", "3001234567", "XX", ""},
+		{"E.164 largo", "+57 3001234567890123", "CO", ""},
+		{"código de país con 0", "+0573001234567", "CO", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizePhone(tt.raw, tt.country)
+			if tt.want == "" {
+				var invalid *InvalidError
+				if !errors.As(err, &invalid) {
+					t.Fatalf("NormalizePhone(%q) = %q, %v; se esperaba un *InvalidError", tt.raw, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("NormalizePhone(%q) = %q, %v; se esperaba %q", tt.raw, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string // vacío = inválido
+	}{
+		{"ana@example.com", "ana@example.com"},
+		{" Ana.Perez@Example.COM ", "Ana.Perez@example.com"},
+		{"ana+alertas@mail.example.co", "ana+alertas@mail.example.co"},
+		{"ana", ""},
+		{"@example.com", ""},
+		{"ana..perez@example.com", ""},
+		{".ana@example.com", ""},
+		{"ana perez@example.com", ""},
+		{"ana@example", ""},
+		{"ana@-example.com", ""},
+		{"ana@example.c", ""},
+		{"ana@example.c0m", ""},
+		{"ana@[1.2.3.4]", ""},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeEmail(tt.raw)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("NormalizeEmail(%q) = %q, se esperaba un error", tt.raw, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizeEmail(%q) = %q, %v; se esperaba %q", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	n := NewNormalizer("co")
+	tests := []struct {
+		channel, raw, want string
+		wantErr            bool
+	}{
+		{"SMS", "3001234567", "+573001234567", false},
+		{"WHATSAPP", "+57 300 123 4567", "+573001234567", false},
+		{"EMAIL", "Ana@Example.com", "Ana@example.com", false},
+		{"PUSH", " token-abc ", "token-abc", false},
+		{"PUSH", "  ", "", true},
+		{"EMAIL", "ana@", "", true},
+	}
+	for _, tt := range tests {
+		got, err := n.Normalize(tt.channel, tt.raw)
+		if tt.wantErr {
+			var invalid *InvalidError
+			if !errors.As(err, &invalid) || !invalid.Permanent() || invalid.Channel != tt.channel {
+				t.Errorf("Normalize(%s, %q) = %q, %v; se esperaba un *InvalidError de %s", tt.channel, tt.raw, got, err, tt.channel)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Normalize(%s, %q) = %q, %v; se esperaba %q", tt.channel, tt.raw, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package contact
+
+import "strings"
+
+// Caracteres permitidos en la parte local sin comillas (atext de RFC 5322)
+const atextSpecials = "!#$%&'*+-/=?^_`{|}~"
+
+// normalizeEmail retorna el email con el dominio en minúsculas, o el motivo del rechazo.
+// No se aceptan partes locales entre comillas ni dominios literales ([1.2.3.4]).
+func normalizeEmail(s string) (string, string) {
+	at := strings.LastIndexByte(s, '@')
+	if at < 0 {
+		return "", "falta '@'"
+	}
+	local, domain := s[:at], strings.ToLower(s[at+1:])
+
+	if local == "" || len(local) > 64 {
+		return "", "parte local vacía o mayor a 64 caracteres"
+	}
+	if strings.HasPrefix(local, ".") || strings.HasSuffix(local, ".") || strings.Contains(local, "..") {
+		return "", "puntos mal ubicados en la parte local"
+	}
+	for _, c := range local {
+		if !isAlnum(c) && c != '.' && !strings.ContainsRune(atextSpecials, c) {
+			return "", "carácter no permitido en la parte local"
+		}
+	}
+
+	if len(domain) > 253 {
+		return "", "dominio mayor a 253 caracteres"
+	}
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return "", "el dominio debe tener al menos un punto"
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 {
+			return "", "etiqueta de dominio vacía o mayor a 63 caracteres"
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return "", "etiqueta de dominio con guion al inicio o al final"
+		}
+		for _, c := range label {
+			if !isAlnum(c) && c != '-' {
+				return "", "carácter no permitido en el dominio"
+			}
+		}
+	}
+	tld := labels[len(labels)-1]
+	if len(tld) < 2 || strings.IndexFunc(tld, func(c rune) bool { return c < 'a' || c > 'z' }) >= 0 {
+		return "", "dominio de primer nivel inválido"
+	}
+
+	return local + "@" + domain, ""
+}
+
+func isAlnum(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
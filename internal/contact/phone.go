@@ -0,0 +1,80 @@
+package contact
+
+import (
+	"strings"
+	"unicode"
+)
+
+// country es el código de llamada de un país y la longitud de sus números nacionales
+// (sin el código de país ni el prefijo troncal)
+type country struct {
+	code           string
+	minLen, maxLen int
+}
+
+// countries son los países admitidos como país por defecto
+var countries = map[string]country{
+	"AR": {"54", 10, 11}, "BO": {"591", 8, 8}, "BR": {"55", 10, 11}, "CA": {"1", 10, 10},
+	"CL": {"56", 9, 9}, "CO": {"57", 8, 10}, "CR": {"506", 8, 8}, "DO": {"1", 10, 10},
+	"EC": {"593", 8, 9}, "ES": {"34", 9, 9}, "GT": {"502", 8, 8}, "HN": {"504", 8, 8},
+	"MX": {"52", 10, 10}, "NI": {"505", 8, 8}, "PA": {"507", 7, 8}, "PE": {"51", 8, 9},
+	"PR": {"1", 10, 10}, "PY": {"595", 7, 9}, "SV": {"503", 8, 8}, "US": {"1", 10, 10},
+	"UY": {"598", 8, 8}, "VE": {"58", 10, 10},
+}
+
+// normalizePhone convierte el teléfono a E.164, o retorna el motivo del rechazo.
+// Acepta "+57 300 123 4567", "0057 3001234567" y números nacionales ("300-123-4567"),
+// a los que antepone el código del país por defecto quitando el prefijo troncal 0. Un
+// número sin "+" que ya empieza por el código del país ("573001234567") no lo repite.
+func normalizePhone(s, defaultCountry string) (string, string) {
+	international := false
+	switch {
+	case strings.HasPrefix(s, "+"):
+		international = true
+		s = s[1:]
+	case strings.HasPrefix(s, "00"):
+		international = true
+		s = s[2:]
+	}
+
+	var digits strings.Builder
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			digits.WriteRune(c)
+		case unicode.IsSpace(c) || c == '-' || c == '.' || c == '(' || c == ')':
+			// separadores habituales
+		default:
+			return "", "carácter no permitido en el teléfono"
+		}
+	}
+	number := digits.String()
+
+	if !international {
+		c, ok := countries[defaultCountry]
+		if !ok {
+			return "", "teléfono sin código de país y país por defecto desconocido"
+		}
+		number = strings.TrimLeft(number, "0")
+		switch {
+		case c.national(number):
+			number = c.code + number
+		case !strings.HasPrefix(number, c.code) || !c.national(number[len(c.code):]):
+			return "", "longitud inválida para un número nacional"
+		}
+	}
+
+	// E.164: hasta 15 dígitos, sin ceros iniciales en el código de país
+	if len(number) < 8 || len(number) > 15 {
+		return "", "longitud inválida para E.164"
+	}
+	if number[0] == '0' {
+		return "", "código de país inválido"
+	}
+	return "+" + number, ""
+}
+
+// national indica si number tiene la longitud de un número nacional del país
+func (c country) national(number string) bool {
+	return len(number) >= c.minLen && len(number) <= c.maxLen
+}
@@ -0,0 +1,113 @@
+// Package digest acumula notificaciones frecuentes (ej: alertas de inicio de sesión) por
+// usuario y template para enviarlas como un único resumen periódico.
+package digest
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule define cómo se resume un template: las notificaciones se acumulan hasta MaxItems
+// o hasta que pase Window desde la primera, y se envían con DigestTemplate
+type Rule struct {
+	DigestTemplate string
+	MaxItems       int
+	Window         time.Duration
+}
+
+// Item es una notificación acumulada
+type Item struct {
+	At   time.Time              `json:"at"`
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// Batch es el resumen en construcción de un usuario, canal y template
+type Batch struct {
+	Key      string    `json:"key"`
+	UserID   string    `json:"user_id"`
+	Channel  string    `json:"channel"`
+	Template string    `json:"template"`
+	To       string    `json:"to"`
+	OpenedAt time.Time `json:"opened_at"`
+	Items    []Item    `json:"items"`
+}
+
+// BatchKey identifica el resumen de un usuario (o destinatario si no hay usuario),
+// canal y template
+func BatchKey(userID, to, channel, template string) string {
+	who := userID
+	if who == "" {
+		who = strings.ToLower(to)
+	}
+	return template + "|" + channel + "|" + who
+}
+
+// Store persiste los resúmenes en construcción
+type Store interface {
+	// Append agrega el item al resumen b.Key (creándolo con los datos de b si no existe)
+	// y retorna el resumen actualizado
+	Append(ctx context.Context, b Batch, item Item) (Batch, error)
+	// Take retira el resumen para enviarlo
+	Take(ctx context.Context, key string) (Batch, bool, error)
+	// Restore devuelve un resumen retirado cuyo envío falló, conservando los items que
+	// se hayan agregado mientras tanto
+	Restore(ctx context.Context, b Batch) error
+	Len() int
+	Close() error
+}
+
+// ParseRules lee las reglas con el formato "template=digest_template:max_items:ventana;..."
+// (ej: "login_alert=login_digest:10:1h")
+func ParseRules(spec string) (map[string]Rule, error) {
+	rules := make(map[string]Rule)
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		template, rest, ok := strings.Cut(entry, "=")
+		template = strings.TrimSpace(template)
+		parts := strings.Split(rest, ":")
+		if !ok || template == "" || len(parts) != 3 {
+			return nil, fmt.Errorf("resumen %q: se esperaba template=digest_template:max_items:ventana", entry)
+		}
+		digestTemplate := strings.TrimSpace(parts[0])
+		if digestTemplate == "" {
+			return nil, fmt.Errorf("resumen %q: falta el template del resumen", entry)
+		}
+		maxItems, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || maxItems < 1 {
+			return nil, fmt.Errorf("resumen %q: max_items inválido", entry)
+		}
+		window, err := time.ParseDuration(strings.TrimSpace(parts[2]))
+		if err != nil || window <= 0 {
+			return nil, fmt.Errorf("resumen %q: ventana inválida", entry)
+		}
+		rules[template] = Rule{DigestTemplate: digestTemplate, MaxItems: maxItems, Window: window}
+	}
+	return rules, nil
+}
+
+func appendItem(batches map[string]Batch, b Batch, item Item) Batch {
+	cur, ok := batches[b.Key]
+	if !ok {
+		cur = b
+		cur.Items = nil
+		if cur.OpenedAt.IsZero() {
+			cur.OpenedAt = item.At
+		}
+	}
+	cur.Items = append(cur.Items, item)
+	batches[b.Key] = cur
+	return cur
+}
+
+func restoreBatch(batches map[string]Batch, b Batch) {
+	if cur, ok := batches[b.Key]; ok {
+		b.Items = append(b.Items, cur.Items...)
+	}
+	batches[b.Key] = b
+}
@@ -0,0 +1,134 @@
+package digest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/andrew/orquestador-notificacion/internal/appendlog"
+)
+
+const (
+	opAppend  = "append"
+	opTake    = "take"
+	opRestore = "restore"
+	opPut     = "put"
+)
+
+// record es una línea del log: un item agregado (Batch lleva los datos del resumen sin
+// items), un resumen retirado, uno restaurado o, tras compactar, un resumen completo
+type record struct {
+	Op    string `json:"op"`
+	Key   string `json:"key,omitempty"`
+	Batch *Batch `json:"batch,omitempty"`
+	Item  *Item  `json:"item,omitempty"`
+}
+
+// FileStore guarda los resúmenes en memoria y registra cada cambio en un log append-only
+// local (JSON por línea), por lo que los resúmenes sobreviven a reinicios
+type FileStore struct {
+	mu      sync.Mutex
+	log     *appendlog.Log[record]
+	batches map[string]Batch
+}
+
+// NewFileStore abre (o crea) el log de resúmenes y recupera los que están en construcción
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{batches: make(map[string]Batch)}
+	log, err := appendlog.Open(path, "resúmenes", s.apply)
+	if err != nil {
+		return nil, err
+	}
+	s.log = log
+	if err := s.rewrite(); err != nil {
+		log.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) apply(rec record) {
+	switch rec.Op {
+	case opAppend:
+		if rec.Batch != nil && rec.Item != nil {
+			appendItem(s.batches, *rec.Batch, *rec.Item)
+		}
+	case opTake:
+		delete(s.batches, rec.Key)
+	case opRestore:
+		if rec.Batch != nil {
+			restoreBatch(s.batches, *rec.Batch)
+		}
+	case opPut:
+		if rec.Batch != nil {
+			s.batches[rec.Batch.Key] = *rec.Batch
+		}
+	}
+}
+
+func (s *FileStore) Append(_ context.Context, b Batch, item Item) (Batch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	meta := b
+	meta.Items = nil
+	if err := s.log.Append(record{Op: opAppend, Batch: &meta, Item: &item}); err != nil {
+		return Batch{}, err
+	}
+	return appendItem(s.batches, b, item), nil
+}
+
+func (s *FileStore) Take(_ context.Context, key string) (Batch, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.batches[key]
+	if !ok {
+		return Batch{}, false, nil
+	}
+	if err := s.log.Append(record{Op: opTake, Key: key}); err != nil {
+		return Batch{}, false, err
+	}
+	delete(s.batches, key)
+
+	// El retiro ya es durable: si la compactación falla el log anterior sigue siendo válido
+	// y se vuelve a intentar en el próximo Take. Retornar el error haría que el llamador
+	// descarte un resumen que ya no está en el store.
+	if s.log.ShouldCompact(len(s.batches)) {
+		_ = s.rewrite()
+	}
+	return b, true, nil
+}
+
+func (s *FileStore) Restore(_ context.Context, b Batch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.log.Append(record{Op: opRestore, Batch: &b}); err != nil {
+		return err
+	}
+	restoreBatch(s.batches, b)
+	return nil
+}
+
+func (s *FileStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.batches)
+}
+
+// rewrite compacta el log dejando un registro por resumen. Requiere el lock tomado
+// (o ejecutarse durante la construcción).
+func (s *FileStore) rewrite() error {
+	recs := make([]record, 0, len(s.batches))
+	for _, b := range s.batches {
+		b := b
+		recs = append(recs, record{Op: opPut, Batch: &b})
+	}
+	return s.log.Rewrite(recs)
+}
+
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.log.Close()
+}
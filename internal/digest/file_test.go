@@ -0,0 +1,118 @@
+package digest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("login_alert=login_digest:10:1h; ; new_device=device_digest:1:30m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Rule{
+		"login_alert": {DigestTemplate: "login_digest", MaxItems: 10, Window: time.Hour},
+		"new_device":  {DigestTemplate: "device_digest", MaxItems: 1, Window: 30 * time.Minute},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("reglas = %v, se esperaba %v", rules, want)
+	}
+	for template, r := range want {
+		if rules[template] != r {
+			t.Errorf("regla %s = %v, se esperaba %v", template, rules[template], r)
+		}
+	}
+
+	for _, spec := range []string{"login_alert", "login_alert=login_digest:10", "login_alert=:10:1h",
+		"login_alert=login_digest:0:1h", "login_alert=login_digest:10:0s"} {
+		if _, err := ParseRules(spec); err == nil {
+			t.Errorf("ParseRules(%q) no falló", spec)
+		}
+	}
+}
+
+func TestFileStoreReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "digests.log")
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	a := Batch{Key: BatchKey("42", "", "EMAIL", "login_alert"), UserID: "42", Channel: "EMAIL", Template: "login_alert"}
+	b := Batch{Key: BatchKey("7", "", "EMAIL", "login_alert"), UserID: "7", Channel: "EMAIL", Template: "login_alert"}
+
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := s.Append(ctx, a, Item{At: at.Add(time.Duration(i) * time.Minute)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Append(ctx, b, Item{At: at}); err != nil {
+		t.Fatal(err)
+	}
+	// a se retira, recibe un item mientras se envía y se restaura tras fallar el envío;
+	// b se envía
+	taken, ok, err := s.Take(ctx, a.Key)
+	if err != nil || !ok {
+		t.Fatalf("Take = %v, %v", ok, err)
+	}
+	if _, err := s.Append(ctx, a, Item{At: at.Add(5 * time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Restore(ctx, taken); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Take(ctx, b.Key); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s, err = NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if n := s.Len(); n != 1 {
+		t.Fatalf("resúmenes tras reabrir = %d, se esperaba 1", n)
+	}
+	got, ok, err := s.Take(ctx, a.Key)
+	if err != nil || !ok {
+		t.Fatalf("Take tras reabrir = %v, %v", ok, err)
+	}
+	if len(got.Items) != 3 || !got.OpenedAt.Equal(at) || !got.Items[2].At.Equal(at.Add(5*time.Minute)) {
+		t.Errorf("resumen tras reabrir = %+v, se esperaban 3 items abiertos en %s", got, at)
+	}
+}
+
+// TestFileStoreTakeCompactionFails verifica que un Take durable no retorne error aunque
+// falle la compactación del log: el llamador descartaría el resumen retirado
+func TestFileStoreTakeCompactionFails(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "digests.log")
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	// Un directorio no vacío en la ruta del temporal hace fallar la compactación
+	if err := os.MkdirAll(filepath.Join(path+".tmp", "x"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	b := Batch{Key: BatchKey("42", "", "EMAIL", "login_alert"), UserID: "42", Channel: "EMAIL", Template: "login_alert"}
+	for i := 0; i < 600; i++ {
+		if _, err := s.Append(ctx, b, Item{At: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		got, ok, err := s.Take(ctx, b.Key)
+		if err != nil || !ok || len(got.Items) != 1 {
+			t.Fatalf("Take %d = %d items, %v, %v", i, len(got.Items), ok, err)
+		}
+	}
+	if s.Len() != 0 {
+		t.Errorf("quedaron %d resúmenes tras retirarlos", s.Len())
+	}
+}
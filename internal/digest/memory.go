@@ -0,0 +1,47 @@
+package digest
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore guarda los resúmenes en memoria (se pierden al reiniciar)
+type MemoryStore struct {
+	mu      sync.Mutex
+	batches map[string]Batch
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{batches: make(map[string]Batch)}
+}
+
+func (s *MemoryStore) Append(_ context.Context, b Batch, item Item) (Batch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return appendItem(s.batches, b, item), nil
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string) (Batch, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.batches[key]
+	delete(s.batches, key)
+	return b, ok, nil
+}
+
+func (s *MemoryStore) Restore(_ context.Context, b Batch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	restoreBatch(s.batches, b)
+	return nil
+}
+
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.batches)
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -1,7 +1,12 @@
 package domain
 
-import "time"
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/errs"
+)
 
 type Event struct {
 	ID        string          `json:"id"`
@@ -9,9 +14,66 @@ type Event struct {
 	Source    string          `json:"source"`
 	Timestamp time.Time       `json:"timestamp"`
 	Payload   json.RawMessage `json:"payload"`
+	// Locale preferido para las notificaciones del evento (ej: es-CO); tiene prioridad
+	// sobre el idioma guardado en las preferencias del usuario
+	Locale string `json:"locale,omitempty"`
+	// CorrelationID une los logs y notificaciones del flujo que originó el evento; vacío
+	// usa el ID del evento
+	CorrelationID string `json:"correlation_id,omitempty"`
+
+	// Programación opcional: el evento se procesa en DeliverAt, o Delay (ej: "24h")
+	// después de Timestamp. DeliverAt tiene prioridad si vienen ambos.
+	DeliverAt *time.Time `json:"deliver_at,omitempty"`
+	Delay     string     `json:"delay,omitempty"`
+	// CancelOn son los tipos de evento que cancelan este evento mientras esté pendiente,
+	// cuando llegan con la misma SubjectKey (ej: USER_VERIFIED cancela el recordatorio)
+	CancelOn []string `json:"cancel_on,omitempty"`
+	// Subject identifica la entidad del evento; vacío usa el id del payload
+	Subject string `json:"subject,omitempty"`
 }
 
-// transform el evento en JSON para un formato legible
+// transform el evento en JSON para un formato legible.
+// Un payload que no decodifica es un error permanente: reintentar no lo corrige.
 func (e *Event) DecodePayload(v interface{}) error {
-	return json.Unmarshal(e.Payload, v)
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return errs.NewPermanent(fmt.Errorf("payload de %s inválido: %w", e.Type, err))
+	}
+	return nil
+}
+
+// ScheduledAt retorna el momento en que debe procesarse el evento, si está programado
+func (e *Event) ScheduledAt() (time.Time, bool, error) {
+	if e.DeliverAt != nil && !e.DeliverAt.IsZero() {
+		return *e.DeliverAt, true, nil
+	}
+	if e.Delay == "" {
+		return time.Time{}, false, nil
+	}
+	d, err := time.ParseDuration(e.Delay)
+	if err != nil || d < 0 {
+		return time.Time{}, false, errs.NewPermanent(fmt.Errorf("delay inválido %q", e.Delay))
+	}
+	base := e.Timestamp
+	if base.IsZero() {
+		base = time.Now()
+	}
+	return base.Add(d), true, nil
+}
+
+// SubjectKey retorna la entidad del evento: Subject, o el campo id del payload
+func (e *Event) SubjectKey() string {
+	if e.Subject != "" {
+		return e.Subject
+	}
+	var p struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(e.Payload, &p); err != nil || len(p.ID) == 0 || string(p.ID) == "null" {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(p.ID, &s); err == nil {
+		return s
+	}
+	return string(p.ID)
 }
@@ -0,0 +1,52 @@
+package domain
+
+import "github.com/google/uuid"
+
+// Channels son los canales que atienden los senders
+var Channels = []string{"EMAIL", "SMS", "WHATSAPP", "PUSH"}
+
+// IsChannel indica si channel es uno de los canales conocidos
+func IsChannel(channel string) bool {
+	for _, c := range Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationEvent es el contrato de eventos publicados hacia los senders
+type NotificationEvent struct {
+	ID       string                 `json:"id"`
+	Type     string                 `json:"type"`
+	Template string                 `json:"template"`
+	To       string                 `json:"to"`
+	Data     map[string]interface{} `json:"data"`
+	// Locale es el locale resuelto para el destinatario (ej: es-CO)
+	Locale string `json:"locale,omitempty"`
+	// CorrelationID es el del evento que originó la notificación (también va en el header
+	// x-correlation-id)
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// Content es el contenido ya renderizado, si el orquestador tiene el template
+	Content *Content `json:"content,omitempty"`
+}
+
+// Content es el resultado de renderizar una versión de un template
+type Content struct {
+	Version int    `json:"version"`
+	Subject string `json:"subject,omitempty"`
+	HTML    string `json:"html,omitempty"`
+	Text    string `json:"text,omitempty"`
+	SMS     string `json:"sms,omitempty"`
+}
+
+// NewNotificationEvent construye el evento de notificación con un ID nuevo
+func NewNotificationEvent(eventType, template, to string, data map[string]interface{}) NotificationEvent {
+	return NotificationEvent{
+		ID:       uuid.New().String(),
+		Type:     eventType,
+		Template: template,
+		To:       to,
+		Data:     data,
+	}
+}
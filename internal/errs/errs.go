@@ -0,0 +1,120 @@
+// Package errs clasifica los errores de handlers y servicios para que el consumer decida
+// qué hacer con el evento:
+//
+//	Retriable: reintento por la escalera de topics de reintento (comportamiento por defecto)
+//	Permanent: reintentar no lo corrige (payload inválido, destinatario inválido); va al DLQ
+//	Throttled: un límite externo pide esperar RetryAfter; el worker se pausa y reintenta
+package errs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Kind es la clase de un error
+type Kind int
+
+const (
+	Retriable Kind = iota
+	Permanent
+	Throttled
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Permanent:
+		return "permanent"
+	case Throttled:
+		return "throttled"
+	default:
+		return "retriable"
+	}
+}
+
+// Error asocia una clase (y para Throttled, el tiempo de espera) a un error
+type Error struct {
+	Kind       Kind
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Kind == Throttled && e.RetryAfter > 0 {
+		return fmt.Sprintf("%v (reintentar en %s)", e.Err, e.RetryAfter)
+	}
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NewPermanent marca err como permanente
+func NewPermanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: Permanent, Err: err}
+}
+
+// NewRetriable marca err como reintentable explícitamente
+func NewRetriable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: Retriable, Err: err}
+}
+
+// NewThrottled marca err como límite de tasa que debe esperar retryAfter antes de reintentar
+func NewThrottled(err error, retryAfter time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: Throttled, RetryAfter: retryAfter, Err: err}
+}
+
+// KindOf clasifica un error. Además de *Error reconoce los errores que implementan
+// Permanent() bool o RetryAfter() time.Duration, y los errores de decodificación JSON
+// (permanentes). El resto se considera reintentable.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+
+	var perm interface{ Permanent() bool }
+	if errors.As(err, &perm) && perm.Permanent() {
+		return Permanent
+	}
+	var throttled interface{ RetryAfter() time.Duration }
+	if errors.As(err, &throttled) {
+		return Throttled
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return Permanent
+	}
+	return Retriable
+}
+
+// RetryAfter retorna la espera pedida por un error Throttled
+func RetryAfter(err error) (time.Duration, bool) {
+	var e *Error
+	if errors.As(err, &e) && e.Kind == Throttled {
+		return e.RetryAfter, true
+	}
+	var throttled interface{ RetryAfter() time.Duration }
+	if errors.As(err, &throttled) {
+		return throttled.RetryAfter(), true
+	}
+	return 0, false
+}
+
+// IsPermanent indica si el error es permanente
+func IsPermanent(err error) bool {
+	return err != nil && KindOf(err) == Permanent
+}
@@ -0,0 +1,396 @@
+// Package expr implementa un evaluador de condiciones pequeño y seguro (sin ejecución de
+// código arbitrario) sobre documentos map[string]interface{}, por ejemplo:
+//
+//	payload.phone != "" && event.source == "mobile-app"
+//	payload.attempts >= 3 || !payload.trusted
+//	event.source in ["mobile-app", "web"]
+//
+// Soporta literales de texto ("..." o '...'), números, true/false/null, rutas con puntos
+// (cada segmento con letras, dígitos y _), los operadores ==, !=, <, <=, >, >=, in, &&, ||,
+// ! y paréntesis. Un campo con otros caracteres (ej: "user-agent") no es accesible.
+// Una ruta inexistente vale null, y null es igual a "" para que `campo != ""` descarte
+// tanto campos vacíos como ausentes.
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expr es una expresión compilada, segura para uso concurrente
+type Expr struct {
+	src  string
+	root node
+}
+
+// Compile analiza la expresión y retorna un error con la posición del problema.
+// Si se indican roots, las rutas deben comenzar por uno de ellos (ej: "event", "payload"),
+// de modo que un error de tipeo falle al compilar en lugar de evaluar siempre a null.
+func Compile(src string, roots ...string) (*Expr, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks, roots: roots}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("posición %d: token inesperado %q", t.pos, t.text)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// MustCompile es como Compile pero entra en pánico si la expresión es inválida.
+// Pensado para expresiones fijas en el código de los handlers.
+func MustCompile(src string, roots ...string) *Expr {
+	e, err := Compile(src, roots...)
+	if err != nil {
+		panic(fmt.Sprintf("expr: %q: %v", src, err))
+	}
+	return e
+}
+
+// Eval evalúa la expresión sobre el documento y retorna su valor de verdad
+func (e *Expr) Eval(doc map[string]interface{}) bool {
+	return truthy(e.root.eval(doc))
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+type node interface {
+	eval(doc map[string]interface{}) interface{}
+}
+
+type literal struct{ v interface{} }
+
+func (n literal) eval(map[string]interface{}) interface{} { return n.v }
+
+type path struct{ parts []string }
+
+func (n path) eval(doc map[string]interface{}) interface{} {
+	var cur interface{} = doc
+	for _, part := range n.parts {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	return cur
+}
+
+type list struct{ items []node }
+
+func (n list) eval(doc map[string]interface{}) interface{} {
+	out := make([]interface{}, len(n.items))
+	for i, it := range n.items {
+		out[i] = it.eval(doc)
+	}
+	return out
+}
+
+type not struct{ x node }
+
+func (n not) eval(doc map[string]interface{}) interface{} { return !truthy(n.x.eval(doc)) }
+
+type logical struct {
+	op   string
+	l, r node
+}
+
+func (n logical) eval(doc map[string]interface{}) interface{} {
+	if n.op == "&&" {
+		return truthy(n.l.eval(doc)) && truthy(n.r.eval(doc))
+	}
+	return truthy(n.l.eval(doc)) || truthy(n.r.eval(doc))
+}
+
+type compare struct {
+	op   string
+	l, r node
+}
+
+func (n compare) eval(doc map[string]interface{}) interface{} {
+	l, r := n.l.eval(doc), n.r.eval(doc)
+	switch n.op {
+	case "==":
+		return equal(l, r)
+	case "!=":
+		return !equal(l, r)
+	case "in":
+		items, ok := r.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, it := range items {
+			if equal(l, it) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Orden: solo entre números o entre textos; tipos mezclados son falsos
+	if lf, ok := toFloat(l); ok {
+		rf, ok := toFloat(r)
+		if !ok {
+			return false
+		}
+		switch n.op {
+		case "<":
+			return lf < rf
+		case "<=":
+			return lf <= rf
+		case ">":
+			return lf > rf
+		default:
+			return lf >= rf
+		}
+	}
+	ls, lok := l.(string)
+	rs, rok := r.(string)
+	if !lok || !rok {
+		return false
+	}
+	switch n.op {
+	case "<":
+		return ls < rs
+	case "<=":
+		return ls <= rs
+	case ">":
+		return ls > rs
+	default:
+		return ls >= rs
+	}
+}
+
+func equal(l, r interface{}) bool {
+	if l == nil {
+		l = ""
+	}
+	if r == nil {
+		r = ""
+	}
+	if lf, ok := toFloat(l); ok {
+		rf, ok := toFloat(r)
+		return ok && lf == rf
+	}
+	switch lv := l.(type) {
+	case string:
+		rv, ok := r.(string)
+		return ok && lv == rv
+	case bool:
+		rv, ok := r.(bool)
+		return ok && lv == rv
+	}
+	return false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	}
+	return 0, false
+}
+
+func truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return t != ""
+	case []interface{}:
+		return len(t) > 0
+	}
+	if f, ok := toFloat(v); ok {
+		return f != 0
+	}
+	return true
+}
+
+var comparisonOps = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+// parser de descenso recursivo:
+//
+//	or      := and ("||" and)*
+//	and     := unary ("&&" unary)*
+//	unary   := "!" unary | cmp
+//	cmp     := operand (("=="|"!="|"<"|"<="|">"|">=") operand | "in" list)?
+//	operand := literal | path | "(" or ")"
+type parser struct {
+	toks  []token
+	pos   int
+	roots []string
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (node, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is(tokOp, "||") {
+		p.next()
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = logical{op: "||", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is(tokOp, "&&") {
+		p.next()
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = logical{op: "&&", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.peek().is(tokOp, "!") {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return not{x: x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	l, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	switch {
+	case t.kind == tokOp && comparisonOps[t.text]:
+		p.next()
+		r, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return compare{op: t.text, l: l, r: r}, nil
+	case t.is(tokIdent, "in"):
+		p.next()
+		r, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return compare{op: "in", l: l, r: r}, nil
+	}
+	return l, nil
+}
+
+func (p *parser) parseList() (node, error) {
+	open := p.next()
+	if !open.is(tokPunct, "[") {
+		return nil, fmt.Errorf("posición %d: se esperaba '[' después de in", open.pos)
+	}
+	var items []node
+	if p.peek().is(tokPunct, "]") {
+		p.next()
+		return list{items: items}, nil
+	}
+	for {
+		it, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+		t := p.next()
+		if t.is(tokPunct, "]") {
+			return list{items: items}, nil
+		}
+		if !t.is(tokPunct, ",") {
+			return nil, fmt.Errorf("posición %d: se esperaba ',' o ']' en la lista", t.pos)
+		}
+	}
+}
+
+func (p *parser) parseOperand() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return literal{v: t.text}, nil
+	case tokNumber:
+		return literal{v: t.num}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return literal{v: true}, nil
+		case "false":
+			return literal{v: false}, nil
+		case "null":
+			return literal{v: nil}, nil
+		case "in":
+			return nil, fmt.Errorf("posición %d: 'in' requiere un operando a la izquierda", t.pos)
+		}
+		parts := strings.Split(t.text, ".")
+		for _, part := range parts {
+			if part == "" {
+				return nil, fmt.Errorf("posición %d: ruta inválida %q", t.pos, t.text)
+			}
+		}
+		if len(p.roots) > 0 && !contains(p.roots, parts[0]) {
+			return nil, fmt.Errorf("posición %d: ruta %q desconocida, debe comenzar por %s", t.pos, t.text, strings.Join(p.roots, ", "))
+		}
+		return path{parts: parts}, nil
+	case tokPunct:
+		if t.text == "(" {
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if c := p.next(); !c.is(tokPunct, ")") {
+				return nil, fmt.Errorf("posición %d: se esperaba ')'", c.pos)
+			}
+			return x, nil
+		}
+	case tokEOF:
+		return nil, fmt.Errorf("posición %d: expresión incompleta", t.pos)
+	}
+	return nil, fmt.Errorf("posición %d: token inesperado %q", t.pos, t.text)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+)
+
+var testDoc = map[string]interface{}{
+	"event": map[string]interface{}{
+		"id":     "evt-1",
+		"source": "mobile-app",
+	},
+	"payload": map[string]interface{}{
+		"phone":    "+573001234567",
+		"email":    "",
+		"attempts": float64(3),
+		"trusted":  false,
+		"tags":     []interface{}{"vip"},
+		"user": map[string]interface{}{
+			"country": "CO",
+		},
+	},
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{`payload.phone != ""`, true},
+		{`payload.email != ""`, false},
+		{`payload.missing == ""`, true},
+		{`payload.missing == null`, true},
+		{`event.source == "mobile-app"`, true},
+		{`event.source == 'web'`, false},
+		{`payload.attempts >= 3`, true},
+		{`payload.attempts > 3`, false},
+		{`payload.attempts < 10 && payload.attempts != 0`, true},
+		{`payload.attempts == "3"`, false},
+		{`!payload.trusted`, true},
+		{`payload.trusted || payload.attempts >= 3`, true},
+		{`payload.trusted && payload.attempts >= 3`, false},
+		{`!(payload.trusted || payload.email != "")`, true},
+		{`event.source in ["mobile-app", "web"]`, true},
+		{`event.source in []`, false},
+		{`payload.user.country == "CO"`, true},
+		{`payload.user.country.code == "CO"`, false},
+		{`payload.attempts > -1`, true},
+		{`payload.phone > payload.attempts`, false},
+		{`"b" > "a"`, true},
+		{`payload.tags`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			e, err := Compile(tt.src, "event", "payload")
+			if err != nil {
+				t.Fatalf("Compile: %v", err)
+			}
+			if got := e.Eval(testDoc); got != tt.want {
+				t.Errorf("Eval = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		src     string
+		wantErr string
+	}{
+		{`payload.phone = ""`, "posición 15: use '=='"},
+		{`payload.phone != "`, "posición 18: texto sin cerrar"},
+		{`payload.phone !=`, "expresión incompleta"},
+		{`(payload.phone != ""`, "se esperaba ')'"},
+		{`event.source in "web"`, "se esperaba '['"},
+		{`event.source in ["web" "app"]`, "se esperaba ',' o ']'"},
+		{`in ["web"]`, "'in' requiere un operando"},
+		{`payload..phone != ""`, "ruta inválida"},
+		{`payload.phone != "" extra`, "token inesperado"},
+		{`payload.phone # 1`, "carácter inesperado"},
+		// Rutas fuera del documento del evento
+		{`paylaod.phone != ""`, `ruta "paylaod.phone" desconocida`},
+		{`trusted`, `ruta "trusted" desconocida`},
+		// '-' no forma parte de los identificadores
+		{`payload.user-agent == "x"`, "carácter inesperado '-'"},
+		{`event.source == mobile-app`, "carácter inesperado '-'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Compile(tt.src, "event", "payload")
+			if err == nil {
+				t.Fatal("se esperaba un error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, se esperaba que contenga %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompileWithoutRoots(t *testing.T) {
+	e, err := Compile(`trusted == true`)
+	if err != nil {
+		t.Fatalf("sin raíces cualquier ruta es válida: %v", err)
+	}
+	if !e.Eval(map[string]interface{}{"trusted": true}) {
+		t.Error("Eval = false, se esperaba true")
+	}
+}
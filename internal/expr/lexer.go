@@ -0,0 +1,108 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+func (t token) is(kind tokenKind, text string) bool {
+	return t.kind == kind && t.text == text
+}
+
+// tokenize separa la expresión en tokens; las posiciones se cuentan desde 1
+func tokenize(src string) ([]token, error) {
+	var toks []token
+	runes := []rune(src)
+	i := 0
+	for i < len(runes) {
+		c := runes[i]
+		pos := i + 1
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '"' || c == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != c; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				sb.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("posición %d: texto sin cerrar", pos)
+			}
+			toks = append(toks, token{kind: tokString, text: sb.String(), pos: pos})
+			i = j + 1
+
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			j := i + 1
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			text := string(runes[i:j])
+			n, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("posición %d: número inválido %q", pos, text)
+			}
+			toks = append(toks, token{kind: tokNumber, text: text, num: n, pos: pos})
+			i = j
+
+		case unicode.IsLetter(c) || c == '_':
+			j := i + 1
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_' || runes[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{kind: tokIdent, text: string(runes[i:j]), pos: pos})
+			i = j
+
+		case strings.ContainsRune("()[],", c):
+			toks = append(toks, token{kind: tokPunct, text: string(c), pos: pos})
+			i++
+
+		default:
+			op := ""
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					op = two
+				}
+			}
+			if op == "" {
+				switch c {
+				case '<', '>', '!':
+					op = string(c)
+				case '=':
+					return nil, fmt.Errorf("posición %d: use '==' para comparar", pos)
+				default:
+					return nil, fmt.Errorf("posición %d: carácter inesperado %q", pos, c)
+				}
+			}
+			toks = append(toks, token{kind: tokOp, text: op, pos: pos})
+			i += len([]rune(op))
+		}
+	}
+	toks = append(toks, token{kind: tokEOF, pos: len(runes) + 1})
+	return toks, nil
+}
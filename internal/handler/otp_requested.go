@@ -32,7 +32,7 @@ func (h *OtpRequestedHandler) Types() []string {
 func (h *OtpRequestedHandler) Handle(ctx context.Context, e *domain.Event) error {
 	var p OtpRequestedPayload
 	if err := e.DecodePayload(&p); err != nil {
-		h.logger.Error("Error al decodificar payload de OTP_REQUESTED", map[string]interface{}{
+		h.logger.WithContext(ctx).Error("Error al decodificar payload de OTP_REQUESTED", map[string]interface{}{
 			"error": err.Error(),
 		})
 		return err
@@ -40,7 +40,7 @@ func (h *OtpRequestedHandler) Handle(ctx context.Context, e *domain.Event) error
 
 	// Usar el método especializado de OTP
 	if err := h.userSvc.SendOtpRecovery(ctx, p.ID, p.Email, p.Name, p.Url); err != nil {
-		h.logger.Error("Fallo al enviar email de recuperación de contraseña con OTP", map[string]interface{}{
+		h.logger.WithContext(ctx).Error("Fallo al enviar email de recuperación de contraseña con OTP", map[string]interface{}{
 			"error":   err.Error(),
 			"user_id": p.ID,
 			"email":   p.Email,
@@ -48,7 +48,7 @@ func (h *OtpRequestedHandler) Handle(ctx context.Context, e *domain.Event) error
 		return err
 	}
 
-	h.logger.Info("Evento OTP_REQUESTED procesado exitosamente", map[string]interface{}{
+	h.logger.WithContext(ctx).Info("Evento OTP_REQUESTED procesado exitosamente", map[string]interface{}{
 		"user_id": p.ID,
 		"email":   p.Email,
 		"url":     p.Url,
@@ -5,6 +5,7 @@ import (
 
 	"github.com/andrew/orquestador-notificacion/internal/domain"
 	"github.com/andrew/orquestador-notificacion/internal/logger"
+	"github.com/andrew/orquestador-notificacion/internal/progress"
 	"github.com/andrew/orquestador-notificacion/internal/service"
 )
 
@@ -31,15 +32,18 @@ func (h *PasswordChangedHandler) Types() []string {
 func (h *PasswordChangedHandler) Handle(ctx context.Context, e *domain.Event) error {
 	var p PasswordChangedPayload
 	if err := e.DecodePayload(&p); err != nil {
-		h.logger.Error("Error al decodificar payload de PASSWORD_CHANGED", map[string]interface{}{
+		h.logger.WithContext(ctx).Error("Error al decodificar payload de PASSWORD_CHANGED", map[string]interface{}{
 			"error": err.Error(),
 		})
 		return err
 	}
 
 	// Enviar alerta por email
-	if err := h.userSvc.SendNotification(ctx, p.ID, p.Email, p.Name, p.Phone, "EMAIL", "password_changed_alert"); err != nil {
-		h.logger.Error("Fallo al enviar alerta de cambio de contraseña por email", map[string]interface{}{
+	err := progress.Step(ctx, "EMAIL", "password_changed_alert", func() error {
+		return h.userSvc.SendNotification(ctx, p.ID, p.Email, p.Name, p.Phone, "EMAIL", "password_changed_alert")
+	})
+	if err != nil {
+		h.logger.WithContext(ctx).Error("Fallo al enviar alerta de cambio de contraseña por email", map[string]interface{}{
 			"error":   err.Error(),
 			"user_id": p.ID,
 			"email":   p.Email,
@@ -48,8 +52,11 @@ func (h *PasswordChangedHandler) Handle(ctx context.Context, e *domain.Event) er
 	}
 
 	// Enviar alerta por sms
-	if err := h.userSvc.SendNotification(ctx, p.ID, p.Email, p.Name, p.Phone, "SMS", "password_changed_alert"); err != nil {
-		h.logger.Error("Fallo al enviar alerta de cambio de contraseña por SMS", map[string]interface{}{
+	err = progress.Step(ctx, "SMS", "password_changed_alert", func() error {
+		return h.userSvc.SendNotification(ctx, p.ID, p.Email, p.Name, p.Phone, "SMS", "password_changed_alert")
+	})
+	if err != nil {
+		h.logger.WithContext(ctx).Error("Fallo al enviar alerta de cambio de contraseña por SMS", map[string]interface{}{
 			"error":   err.Error(),
 			"user_id": p.ID,
 			"phone":   p.Phone,
@@ -57,7 +64,7 @@ func (h *PasswordChangedHandler) Handle(ctx context.Context, e *domain.Event) er
 		return err
 	}
 
-	h.logger.Info("Evento PASSWORD_CHANGED procesado exitosamente", map[string]interface{}{
+	h.logger.WithContext(ctx).Info("Evento PASSWORD_CHANGED procesado exitosamente", map[string]interface{}{
 		"user_id": p.ID,
 		"email":   p.Email,
 	})
@@ -3,6 +3,9 @@ package handler
 import (
 	"context"
 	"errors"
+	"sort"
+	"time"
+
 	"github.com/andrew/orquestador-notificacion/internal/domain"
 )
 
@@ -14,11 +17,17 @@ type EventHandler interface {
 }
 
 type Registry struct {
-	handlers map[string][]EventHandler
+	handlers      map[string][]EventHandler
+	retryPolicies map[string]RetryPolicy
+	defaultRetry  RetryPolicy
 }
 
 func NewRegistry() *Registry {
-	return &Registry{handlers: make(map[string][]EventHandler)}
+	return &Registry{
+		handlers:      make(map[string][]EventHandler),
+		retryPolicies: make(map[string]RetryPolicy),
+		defaultRetry:  DefaultRetryPolicy,
+	}
 }
 
 func (r *Registry) Register(h EventHandler) {
@@ -34,3 +43,47 @@ func (r *Registry) GetHandlers(eventType string) ([]EventHandler, error) {
 	}
 	return hs, nil
 }
+
+// Has indica si hay algún handler registrado para el tipo de evento (incluidos los tipos
+// del archivo de routing, que se registran con un RuleHandler cada uno)
+func (r *Registry) Has(eventType string) bool {
+	return len(r.handlers[eventType]) > 0
+}
+
+// SetRetryPolicy declara la política de reintentos para un tipo de evento
+func (r *Registry) SetRetryPolicy(eventType string, p RetryPolicy) {
+	r.retryPolicies[eventType] = p
+}
+
+// SetDefaultRetryPolicy reemplaza la política usada por los tipos sin política propia
+func (r *Registry) SetDefaultRetryPolicy(p RetryPolicy) {
+	r.defaultRetry = p
+}
+
+// RetryPolicy retorna la política de reintentos aplicable a un tipo de evento
+func (r *Registry) RetryPolicy(eventType string) RetryPolicy {
+	if p, ok := r.retryPolicies[eventType]; ok {
+		return p
+	}
+	return r.defaultRetry
+}
+
+// RetryDelays retorna todas las esperas distintas declaradas, para crear sus topics
+func (r *Registry) RetryDelays() []time.Duration {
+	seen := make(map[time.Duration]bool)
+	var delays []time.Duration
+	add := func(p RetryPolicy) {
+		for _, d := range p.Delays {
+			if !seen[d] {
+				seen[d] = true
+				delays = append(delays, d)
+			}
+		}
+	}
+	add(r.defaultRetry)
+	for _, p := range r.retryPolicies {
+		add(p)
+	}
+	sort.Slice(delays, func(i, j int) bool { return delays[i] < delays[j] })
+	return delays
+}
@@ -0,0 +1,31 @@
+package handler
+
+import "time"
+
+// RetryPolicy define la escalera de reintentos de un tipo de evento.
+// Cada elemento de Delays corresponde a un topic de reintento (ej: user-events.retry.30s);
+// al agotarlos, el evento se envía al DLQ.
+type RetryPolicy struct {
+	Delays []time.Duration
+}
+
+// MaxAttempts retorna el total de intentos: el original más uno por cada escalón
+func (p RetryPolicy) MaxAttempts() int {
+	return len(p.Delays) + 1
+}
+
+// DefaultRetryPolicy es la escalera usada cuando un tipo de evento no declara la suya
+var DefaultRetryPolicy = RetryPolicy{
+	Delays: []time.Duration{30 * time.Second, 5 * time.Minute, time.Hour},
+}
+
+// ExponentialBackoff genera una escalera de steps esperas: base, base*factor, base*factor^2...
+func ExponentialBackoff(base time.Duration, factor float64, steps int) []time.Duration {
+	delays := make([]time.Duration, 0, steps)
+	d := base
+	for i := 0; i < steps; i++ {
+		delays = append(delays, d)
+		d = time.Duration(float64(d) * factor)
+	}
+	return delays
+}
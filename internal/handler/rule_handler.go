@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/andrew/orquestador-notificacion/internal/domain"
+	"github.com/andrew/orquestador-notificacion/internal/logger"
+	"github.com/andrew/orquestador-notificacion/internal/progress"
+	"github.com/andrew/orquestador-notificacion/internal/routing"
+	"github.com/andrew/orquestador-notificacion/internal/service"
+)
+
+// defaultUserIDPath es la ruta del id de usuario cuando la regla no la declara
+const defaultUserIDPath = "payload.id"
+
+// RuleHandler envía las notificaciones declaradas en el archivo de routing para un tipo de evento
+type RuleHandler struct {
+	eventType string
+	rules     []routing.Rule
+	userSvc   service.UserService
+	logger    *logger.Logger
+}
+
+func NewRuleHandler(eventType string, rules []routing.Rule, us service.UserService, log *logger.Logger) *RuleHandler {
+	return &RuleHandler{eventType: eventType, rules: rules, userSvc: us, logger: log}
+}
+
+func (h *RuleHandler) Types() []string {
+	return []string{h.eventType}
+}
+
+func (h *RuleHandler) Handle(ctx context.Context, e *domain.Event) error {
+	doc, err := routing.Document(e)
+	if err != nil {
+		h.logger.WithContext(ctx).Error("Error al decodificar payload para routing", map[string]interface{}{
+			"error":      err.Error(),
+			"event_type": e.Type,
+		})
+		return err
+	}
+
+	for i, r := range h.rules {
+		if !r.Matches(doc) {
+			h.logger.WithContext(ctx).Debug("Regla de routing omitida por su condición", map[string]interface{}{
+				"rule": routing.RuleRef(h.eventType, i, r),
+				"when": r.When,
+			})
+			continue
+		}
+
+		n := buildNotification(doc, r)
+		// El paso incluye el índice de la regla: dos reglas con el mismo canal y template
+		// (ej: con distintas condiciones o destinatarios) son envíos distintos
+		err := progress.Step(ctx, r.Channel, ruleStep(i, r), func() error {
+			return h.userSvc.Deliver(ctx, n)
+		})
+		if err != nil {
+			h.logger.WithContext(ctx).Error("Fallo al enviar notificación de regla de routing", map[string]interface{}{
+				"error":   err.Error(),
+				"rule":    routing.RuleRef(h.eventType, i, r),
+				"user_id": n.UserID,
+			})
+			return err
+		}
+	}
+
+	h.logger.WithContext(ctx).Info("Evento procesado por reglas de routing", map[string]interface{}{
+		"event_type": e.Type,
+		"rules":      len(h.rules),
+	})
+	return nil
+}
+
+// ruleStep identifica el envío de la regla en el progreso del evento, ej: login_alert#1
+func ruleStep(index int, r routing.Rule) string {
+	return r.Template + "#" + strconv.Itoa(index)
+}
+
+func buildNotification(doc map[string]interface{}, r routing.Rule) service.Notification {
+	userIDPath := r.UserID
+	if userIDPath == "" {
+		userIDPath = defaultUserIDPath
+	}
+
+	data := make(map[string]interface{}, len(r.Data))
+	for key, path := range r.Data {
+		if v, ok := routing.Lookup(doc, path); ok {
+			data[key] = v
+		}
+	}
+
+	contacts := make(map[string]string, len(r.Contacts))
+	for channel, path := range r.Contacts {
+		if v := routing.LookupString(doc, path); v != "" {
+			contacts[channel] = v
+		}
+	}
+
+	return service.Notification{
+		UserID:   routing.LookupString(doc, userIDPath),
+		Channel:  r.Channel,
+		Template: r.Template,
+		To:       routing.LookupString(doc, r.Recipient),
+		Data:     data,
+		Contacts: contacts,
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/andrew/orquestador-notificacion/internal/domain"
 	"github.com/andrew/orquestador-notificacion/internal/logger"
+	"github.com/andrew/orquestador-notificacion/internal/progress"
 	"github.com/andrew/orquestador-notificacion/internal/service"
 )
 
@@ -31,15 +32,18 @@ func (h *UserLoginHandler) Types() []string {
 func (h *UserLoginHandler) Handle(ctx context.Context, e *domain.Event) error {
 	var p UserLoginPayload
 	if err := e.DecodePayload(&p); err != nil {
-		h.logger.Error("Error al decodificar payload de USER_LOGIN", map[string]interface{}{
+		h.logger.WithContext(ctx).Error("Error al decodificar payload de USER_LOGIN", map[string]interface{}{
 			"error": err.Error(),
 		})
 		return err
 	}
 
 	// Notificación por EMAIL
-	if err := h.userSvc.SendNotification(ctx, p.ID, p.Email, p.Name, p.Phone, "EMAIL", "login_alert"); err != nil {
-		h.logger.Error("Fallo al enviar notificación de login por email", map[string]interface{}{
+	err := progress.Step(ctx, "EMAIL", "login_alert", func() error {
+		return h.userSvc.SendNotification(ctx, p.ID, p.Email, p.Name, p.Phone, "EMAIL", "login_alert")
+	})
+	if err != nil {
+		h.logger.WithContext(ctx).Error("Fallo al enviar notificación de login por email", map[string]interface{}{
 			"error":   err.Error(),
 			"user_id": p.ID,
 			"email":   p.Email,
@@ -48,8 +52,11 @@ func (h *UserLoginHandler) Handle(ctx context.Context, e *domain.Event) error {
 	}
 
 	// Notificación por SMS
-	if err := h.userSvc.SendNotification(ctx, p.ID, p.Email, p.Name, p.Phone, "SMS", "login_alert"); err != nil {
-		h.logger.Error("Fallo al enviar notificación de login por SMS", map[string]interface{}{
+	err = progress.Step(ctx, "SMS", "login_alert", func() error {
+		return h.userSvc.SendNotification(ctx, p.ID, p.Email, p.Name, p.Phone, "SMS", "login_alert")
+	})
+	if err != nil {
+		h.logger.WithContext(ctx).Error("Fallo al enviar notificación de login por SMS", map[string]interface{}{
 			"error":   err.Error(),
 			"user_id": p.ID,
 			"phone":   p.Phone,
@@ -57,7 +64,7 @@ func (h *UserLoginHandler) Handle(ctx context.Context, e *domain.Event) error {
 		return err
 	}
 
-	h.logger.Info("Evento USER_LOGIN procesado exitosamente", map[string]interface{}{
+	h.logger.WithContext(ctx).Info("Evento USER_LOGIN procesado exitosamente", map[string]interface{}{
 		"user_id": p.ID,
 		"email":   p.Email,
 		"phone":   p.Phone,
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/andrew/orquestador-notificacion/internal/domain"
+	"github.com/andrew/orquestador-notificacion/internal/logger"
+	"github.com/andrew/orquestador-notificacion/internal/preferences"
+	"github.com/andrew/orquestador-notificacion/internal/service"
+)
+
+type UserPreferencesUpdatedPayload struct {
+	ID              int                     `json:"id"`
+	Channels        []string                `json:"channels"`
+	OptOutTemplates []string                `json:"opt_out_templates"`
+	Language        string                  `json:"language"`
+	TimeZone        string                  `json:"time_zone"`
+	QuietHours      *preferences.QuietHours `json:"quiet_hours"`
+}
+
+type UserPreferencesUpdatedHandler struct {
+	userSvc service.UserService
+	logger  *logger.Logger
+}
+
+func NewUserPreferencesUpdatedHandler(us service.UserService, log *logger.Logger) *UserPreferencesUpdatedHandler {
+	return &UserPreferencesUpdatedHandler{userSvc: us, logger: log}
+}
+
+func (h *UserPreferencesUpdatedHandler) Types() []string {
+	return []string{"USER_PREFERENCES_UPDATED"}
+}
+
+func (h *UserPreferencesUpdatedHandler) Handle(ctx context.Context, e *domain.Event) error {
+	var p UserPreferencesUpdatedPayload
+	if err := e.DecodePayload(&p); err != nil {
+		h.logger.WithContext(ctx).Error("Error al decodificar payload de USER_PREFERENCES_UPDATED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	prefs := preferences.Preferences{
+		UserID:          strconv.Itoa(p.ID),
+		Channels:        p.Channels,
+		OptOutTemplates: p.OptOutTemplates,
+		Language:        p.Language,
+		TimeZone:        p.TimeZone,
+		QuietHours:      p.QuietHours,
+		UpdatedAt:       e.Timestamp,
+	}
+	if err := h.userSvc.UpdatePreferences(ctx, prefs); err != nil {
+		h.logger.WithContext(ctx).Error("Fallo al actualizar preferencias del usuario", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": p.ID,
+		})
+		return err
+	}
+
+	h.logger.WithContext(ctx).Info("Evento USER_PREFERENCES_UPDATED procesado exitosamente", map[string]interface{}{
+		"user_id": p.ID,
+	})
+	return nil
+}
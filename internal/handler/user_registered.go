@@ -32,7 +32,7 @@ func (h *UserRegisteredHandler) Types() []string {
 func (h *UserRegisteredHandler) Handle(ctx context.Context, e *domain.Event) error {
 	var p UserRegisteredPayload
 	if err := e.DecodePayload(&p); err != nil {
-		h.logger.Error("Error al decodificar payload de USER_REGISTERED", map[string]interface{}{
+		h.logger.WithContext(ctx).Error("Error al decodificar payload de USER_REGISTERED", map[string]interface{}{
 			"error": err.Error(),
 		})
 		return err
@@ -40,7 +40,7 @@ func (h *UserRegisteredHandler) Handle(ctx context.Context, e *domain.Event) err
 
 	// Delegar la lógica de negocio al service (Single Responsibility)
 	if err := h.userSvc.OnUserRegistered(ctx, p.ID, p.Email, p.Name, p.Phone, p.Url); err != nil {
-		h.logger.Error("Fallo en servicio de usuario para USER_REGISTERED", map[string]interface{}{
+		h.logger.WithContext(ctx).Error("Fallo en servicio de usuario para USER_REGISTERED", map[string]interface{}{
 			"error":   err.Error(),
 			"user_id": p.ID,
 			"email":   p.Email,
@@ -48,10 +48,10 @@ func (h *UserRegisteredHandler) Handle(ctx context.Context, e *domain.Event) err
 		return err
 	}
 
-	h.logger.Info("Evento USER_REGISTERED procesado exitosamente", map[string]interface{}{
+	h.logger.WithContext(ctx).Info("Evento USER_REGISTERED procesado exitosamente", map[string]interface{}{
 		"user_id": p.ID,
 		"email":   p.Email,
-		"url":      p.Url,
+		"url":     p.Url,
 	})
 	return nil
 }
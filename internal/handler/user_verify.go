@@ -36,7 +36,7 @@ func (h *UserVerifiedHandler) Types() []string {
 func (h *UserVerifiedHandler) Handle(ctx context.Context, e *domain.Event) error {
 	var p UserVerifiedPayload
 	if err := e.DecodePayload(&p); err != nil {
-		h.logger.Error("Error al decodificar payload de USER_VERIFIED", map[string]interface{}{
+		h.logger.WithContext(ctx).Error("Error al decodificar payload de USER_VERIFIED", map[string]interface{}{
 			"error": err.Error(),
 		})
 		return err
@@ -44,7 +44,7 @@ func (h *UserVerifiedHandler) Handle(ctx context.Context, e *domain.Event) error
 
 	// Delegamos al UserService (por ejemplo, enviar email de cuenta verificada)
 	if err := h.userSvc.OnUserVerified(ctx, p.ID, p.Email, p.Name, p.Phone); err != nil {
-		h.logger.Error("Fallo en servicio de usuario para USER_VERIFIED", map[string]interface{}{
+		h.logger.WithContext(ctx).Error("Fallo en servicio de usuario para USER_VERIFIED", map[string]interface{}{
 			"error":   err.Error(),
 			"user_id": p.ID,
 			"email":   p.Email,
@@ -52,7 +52,7 @@ func (h *UserVerifiedHandler) Handle(ctx context.Context, e *domain.Event) error
 		return err
 	}
 
-	h.logger.Info("Evento USER_VERIFIED procesado exitosamente", map[string]interface{}{
+	h.logger.WithContext(ctx).Info("Evento USER_VERIFIED procesado exitosamente", map[string]interface{}{
 		"user_id": p.ID,
 		"email":   p.Email,
 	})
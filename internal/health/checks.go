@@ -0,0 +1,25 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// WritableDirs verifica que se pueda escribir en los directorios de los stores en archivo
+// (disco lleno o volumen de solo lectura)
+func WritableDirs(dirs ...string) Check {
+	return func(_ context.Context) (map[string]interface{}, error) {
+		data := map[string]interface{}{"dirs": dirs}
+		for _, dir := range dirs {
+			f, err := os.CreateTemp(dir, ".health-*")
+			if err != nil {
+				return data, fmt.Errorf("directorio %s no escribible: %w", dir, err)
+			}
+			name := f.Name()
+			f.Close()
+			os.Remove(name)
+		}
+		return data, nil
+	}
+}
@@ -0,0 +1,169 @@
+// Package health implementa el registro de checks de readiness y liveness que exponen
+// los endpoints /health, /health/ready y /health/live.
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Estados de un check
+const (
+	StatusUp   = "UP"
+	StatusDown = "DOWN"
+)
+
+// checkTimeout acota cada check para que un componente colgado no bloquee la respuesta
+const checkTimeout = 3 * time.Second
+
+// Kind indica qué endpoint considera el check
+type Kind int
+
+const (
+	// Readiness: el servicio puede procesar eventos (broker alcanzable, producer escribiendo)
+	Readiness Kind = iota
+	// Liveness: el proceso sigue avanzando (workers sin trabarse); un fallo implica reiniciar
+	Liveness
+)
+
+func (k Kind) String() string {
+	if k == Liveness {
+		return "liveness"
+	}
+	return "readiness"
+}
+
+// Check verifica un componente. Un error marca el check como DOWN; data se expone en la
+// respuesta en ambos casos.
+type Check func(ctx context.Context) (map[string]interface{}, error)
+
+// Result es el resultado de un check. Since es el momento desde el que mantiene su estado.
+type Result struct {
+	Name   string
+	Kind   Kind
+	Status string
+	Data   map[string]interface{}
+	Since  time.Time
+}
+
+type registered struct {
+	name   string
+	kind   Kind
+	check  Check
+	status string
+	since  time.Time
+}
+
+// Registry agrupa los checks registrados por los componentes
+type Registry struct {
+	mu     sync.Mutex
+	checks []*registered
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register agrega un check; el nombre identifica al componente en la respuesta
+func (r *Registry) Register(name string, kind Kind, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, &registered{name: name, kind: kind, check: check, status: StatusUp, since: time.Now()})
+}
+
+// Run ejecuta en paralelo los checks de los tipos indicados (todos si no se indica ninguno)
+// y retorna sus resultados ordenados por tipo y nombre
+func (r *Registry) Run(ctx context.Context, kinds ...Kind) []Result {
+	r.mu.Lock()
+	var selected []*registered
+	for _, c := range r.checks {
+		if len(kinds) == 0 || containsKind(kinds, c.kind) {
+			selected = append(selected, c)
+		}
+	}
+	r.mu.Unlock()
+
+	results := make([]Result, len(selected))
+	var wg sync.WaitGroup
+	for i, c := range selected {
+		wg.Add(1)
+		go func(i int, c *registered) {
+			defer wg.Done()
+			results[i] = r.run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Kind != results[j].Kind {
+			return results[i].Kind < results[j].Kind
+		}
+		return results[i].Name < results[j].Name
+	})
+	return results
+}
+
+func (r *Registry) run(ctx context.Context, c *registered) Result {
+	checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	data, err := safeCheck(checkCtx, c.check)
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	status := StatusUp
+	if err != nil {
+		status = StatusDown
+		data["error"] = err.Error()
+	}
+
+	r.mu.Lock()
+	if c.status != status {
+		c.status = status
+		c.since = time.Now()
+	}
+	since := c.since
+	r.mu.Unlock()
+
+	return Result{Name: c.name, Kind: c.kind, Status: status, Data: data, Since: since}
+}
+
+// safeCheck ejecuta el check respetando el timeout aunque el componente no lo haga
+func safeCheck(ctx context.Context, check Check) (map[string]interface{}, error) {
+	type outcome struct {
+		data map[string]interface{}
+		err  error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		data, err := check(ctx)
+		done <- outcome{data, err}
+	}()
+	select {
+	case o := <-done:
+		return o.data, o.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Healthy indica si todos los resultados están UP
+func Healthy(results []Result) bool {
+	for _, r := range results {
+		if r.Status != StatusUp {
+			return false
+		}
+	}
+	return true
+}
+
+func containsKind(kinds []Kind, k Kind) bool {
+	for _, kind := range kinds {
+		if kind == k {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Heartbeats sigue a goroutines de larga vida (ej: workers del consumer). Un componente
+// ocupado más de stuckAfter se considera trabado; uno que terminó sin un apagado ordenado,
+// caído. Los componentes ociosos (esperando mensajes o en una pausa deliberada) no cuentan.
+type Heartbeats struct {
+	mu         sync.Mutex
+	stuckAfter time.Duration
+	beats      map[string]*beat
+	now        func() time.Time
+}
+
+type beat struct {
+	busySince time.Time
+	exited    bool
+}
+
+func NewHeartbeats(stuckAfter time.Duration) *Heartbeats {
+	return &Heartbeats{stuckAfter: stuckAfter, beats: make(map[string]*beat), now: time.Now}
+}
+
+// Start registra un componente activo y ocioso
+func (h *Heartbeats) Start(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.beats[name] = &beat{}
+}
+
+// Busy marca que el componente empezó una unidad de trabajo
+func (h *Heartbeats) Busy(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if b, ok := h.beats[name]; ok {
+		b.busySince = h.now()
+	}
+}
+
+// Idle marca que el componente terminó su trabajo o entró en una espera deliberada
+func (h *Heartbeats) Idle(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if b, ok := h.beats[name]; ok {
+		b.busySince = time.Time{}
+	}
+}
+
+// Exit registra el fin del componente. Un fin ordenado (apagado) lo elimina; uno
+// inesperado lo deja marcado como caído.
+func (h *Heartbeats) Exit(name string, graceful bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if graceful {
+		delete(h.beats, name)
+		return
+	}
+	if b, ok := h.beats[name]; ok {
+		b.exited = true
+		b.busySince = time.Time{}
+	}
+}
+
+// Check es un health.Check de liveness: falla si no hay componentes activos o alguno
+// está trabado o caído
+func (h *Heartbeats) Check(_ context.Context) (map[string]interface{}, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	var stuck, exited []string
+	alive, busy := 0, 0
+	for name, b := range h.beats {
+		switch {
+		case b.exited:
+			exited = append(exited, name)
+		case !b.busySince.IsZero():
+			alive++
+			busy++
+			if now.Sub(b.busySince) > h.stuckAfter {
+				stuck = append(stuck, name)
+			}
+		default:
+			alive++
+		}
+	}
+	sort.Strings(stuck)
+	sort.Strings(exited)
+
+	data := map[string]interface{}{
+		"alive":       alive,
+		"busy":        busy,
+		"stuck_after": h.stuckAfter.String(),
+	}
+	if len(stuck) > 0 {
+		data["stuck"] = stuck
+	}
+	if len(exited) > 0 {
+		data["exited"] = exited
+	}
+
+	switch {
+	case len(stuck) > 0:
+		return data, fmt.Errorf("componentes trabados: %s", strings.Join(stuck, ", "))
+	case len(exited) > 0:
+		return data, fmt.Errorf("componentes caídos: %s", strings.Join(exited, ", "))
+	case alive == 0:
+		return data, fmt.Errorf("sin componentes activos")
+	}
+	return data, nil
+}
@@ -0,0 +1,96 @@
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Bundle contiene los catálogos de mensajes de cada locale, cargados desde
+// <dir>/<locale>.yaml con claves anidadas por template, por ejemplo es.yaml:
+//
+//	login_alert:
+//	  title: "Nuevo inicio de sesión"
+//	  unknown_device: "Dispositivo desconocido"
+type Bundle struct {
+	defaultLocale string
+	catalogs      map[string]map[string]string // locale → clave plana (template.clave) → mensaje
+}
+
+// LoadBundle lee todos los catálogos del directorio. defaultLocale se usa cuando un
+// mensaje no existe en ningún locale de la cadena pedida.
+func LoadBundle(dir, defaultLocale string) (*Bundle, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	b := &Bundle{defaultLocale: Normalize(defaultLocale), catalogs: make(map[string]map[string]string)}
+	for _, path := range files {
+		locale := Normalize(strings.TrimSuffix(filepath.Base(path), ".yaml"))
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("leer catálogo %s: %w", path, err)
+		}
+		var tree map[string]interface{}
+		if err := yaml.Unmarshal(raw, &tree); err != nil {
+			return nil, fmt.Errorf("parsear catálogo %s: %w", path, err)
+		}
+		flat := make(map[string]string)
+		if err := flatten("", tree, flat); err != nil {
+			return nil, fmt.Errorf("catálogo %s: %w", path, err)
+		}
+		b.catalogs[locale] = flat
+	}
+	return b, nil
+}
+
+func flatten(prefix string, tree map[string]interface{}, out map[string]string) error {
+	for key, v := range tree {
+		full := key
+		if prefix != "" {
+			full = prefix + "." + key
+		}
+		switch t := v.(type) {
+		case map[string]interface{}:
+			if err := flatten(full, t, out); err != nil {
+				return err
+			}
+		case string:
+			out[full] = t
+		default:
+			return fmt.Errorf("clave %s: se esperaba un texto", full)
+		}
+	}
+	return nil
+}
+
+// Locales retorna los locales con catálogo
+func (b *Bundle) Locales() []string {
+	out := make([]string, 0, len(b.catalogs))
+	for locale := range b.catalogs {
+		out = append(out, locale)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Messages retorna los mensajes del template resueltos para el locale: cada clave se
+// busca en la cadena del locale y luego en el locale por defecto
+func (b *Bundle) Messages(locale, template string) map[string]string {
+	chain := append(Chain(locale), Chain(b.defaultLocale)...)
+	prefix := template + "."
+	out := make(map[string]string)
+	// Se recorre de lo más general a lo más específico para que lo específico prevalezca
+	for i := len(chain) - 1; i >= 0; i-- {
+		for key, msg := range b.catalogs[chain[i]] {
+			if strings.HasPrefix(key, prefix) {
+				out[strings.TrimPrefix(key, prefix)] = msg
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,52 @@
+// Package i18n resuelve el locale de cada notificación y sus mensajes traducidos.
+// Los locales siguen BCP 47 simplificado (idioma y región opcional: "es", "es-CO", "en-US")
+// y se resuelven de lo más específico a lo más general: es-CO → es.
+package i18n
+
+import (
+	"context"
+	"strings"
+)
+
+// Normalize unifica la escritura del locale: "es_co" y "ES-co" se convierten en "es-CO"
+func Normalize(locale string) string {
+	locale = strings.TrimSpace(strings.ReplaceAll(locale, "_", "-"))
+	if locale == "" {
+		return ""
+	}
+	lang, region, ok := strings.Cut(locale, "-")
+	lang = strings.ToLower(lang)
+	if !ok || region == "" {
+		return lang
+	}
+	return lang + "-" + strings.ToUpper(region)
+}
+
+// Chain retorna los locales a probar en orden: "es-CO" → ["es-CO", "es"]
+func Chain(locale string) []string {
+	locale = Normalize(locale)
+	if locale == "" {
+		return nil
+	}
+	chain := []string{locale}
+	if lang, _, ok := strings.Cut(locale, "-"); ok {
+		chain = append(chain, lang)
+	}
+	return chain
+}
+
+type localeKey struct{}
+
+// WithLocale asocia al contexto el locale del evento en proceso
+func WithLocale(ctx context.Context, locale string) context.Context {
+	if locale = Normalize(locale); locale == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// FromContext retorna el locale del evento en proceso, o "" si no declaró uno
+func FromContext(ctx context.Context) string {
+	locale, _ := ctx.Value(localeKey{}).(string)
+	return locale
+}
@@ -0,0 +1,100 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/appendlog"
+)
+
+type fileRecord struct {
+	Key       string    `json:"key"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// FileStore es un store embebido respaldado por un archivo append-only (JSON por línea).
+// Al abrirse carga las claves vigentes en memoria; sobrevive a reinicios del proceso.
+type FileStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	log     *appendlog.Log[fileRecord]
+	entries map[string]time.Time
+	now     func() time.Time
+}
+
+// NewFileStore abre (o crea) el archivo indicado y carga las claves que no han expirado
+func NewFileStore(path string, ttl time.Duration) (*FileStore, error) {
+	s := &FileStore{
+		ttl:     ttl,
+		entries: make(map[string]time.Time),
+		now:     time.Now,
+	}
+	now := s.now()
+	log, err := appendlog.Open(path, "store de idempotencia", func(rec fileRecord) {
+		if rec.ExpiresAt.After(now) {
+			s.entries[rec.Key] = rec.ExpiresAt
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.log = log
+	if err := s.compact(); err != nil {
+		log.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) Seen(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt, ok := s.entries[key]
+	if !ok {
+		return false, nil
+	}
+	if s.now().After(expiresAt) {
+		delete(s.entries, key)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (s *FileStore) MarkDone(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec := fileRecord{Key: key, ExpiresAt: s.now().Add(s.ttl)}
+	if err := s.log.Append(rec); err != nil {
+		return err
+	}
+	s.entries[key] = rec.ExpiresAt
+
+	if s.log.ShouldCompact(len(s.entries)) {
+		return s.compact()
+	}
+	return nil
+}
+
+// compact reescribe el archivo solo con las claves vigentes. Debe llamarse con el lock tomado
+// (o durante la construcción).
+func (s *FileStore) compact() error {
+	now := s.now()
+	recs := make([]fileRecord, 0, len(s.entries))
+	for key, expiresAt := range s.entries {
+		if now.After(expiresAt) {
+			delete(s.entries, key)
+			continue
+		}
+		recs = append(recs, fileRecord{Key: key, ExpiresAt: expiresAt})
+	}
+	return s.log.Rewrite(recs)
+}
+
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.log.Close()
+}
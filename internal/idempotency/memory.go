@@ -0,0 +1,89 @@
+package idempotency
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	key       string
+	expiresAt time.Time
+}
+
+// MemoryStore es un store en memoria con desalojo LRU y expiración por TTL
+type MemoryStore struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List // frente = más reciente
+	entries  map[string]*list.Element
+	now      func() time.Time
+}
+
+// NewMemoryStore crea un store que guarda como máximo capacity claves durante ttl
+func NewMemoryStore(capacity int, ttl time.Duration) *MemoryStore {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &MemoryStore{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+func (s *MemoryStore) Seen(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[key]
+	if !ok {
+		return false, nil
+	}
+	if s.now().After(el.Value.(*memoryEntry).expiresAt) {
+		s.remove(el)
+		return false, nil
+	}
+	s.order.MoveToFront(el)
+	return true, nil
+}
+
+func (s *MemoryStore) MarkDone(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt := s.now().Add(s.ttl)
+	if el, ok := s.entries[key]; ok {
+		el.Value.(*memoryEntry).expiresAt = expiresAt
+		s.order.MoveToFront(el)
+		return nil
+	}
+
+	s.entries[key] = s.order.PushFront(&memoryEntry{key: key, expiresAt: expiresAt})
+	for s.order.Len() > s.capacity {
+		s.remove(s.order.Back())
+	}
+	return nil
+}
+
+// Forget elimina la clave, por ejemplo para liberar una reserva cuyo trabajo falló
+func (s *MemoryStore) Forget(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		s.remove(el)
+	}
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func (s *MemoryStore) remove(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.entries, el.Value.(*memoryEntry).key)
+}
@@ -0,0 +1,13 @@
+package idempotency
+
+import "context"
+
+// Store registra las claves (ej: domain.Event.ID) ya procesadas por completo.
+// Las implementaciones deben ser seguras para uso concurrente.
+type Store interface {
+	// Seen indica si la clave ya fue marcada como completada y no ha expirado
+	Seen(ctx context.Context, key string) (bool, error)
+	// MarkDone marca la clave como completada durante el TTL del store
+	MarkDone(ctx context.Context, key string) error
+	Close() error
+}
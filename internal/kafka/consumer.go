@@ -1,26 +1,98 @@
+// Package kafka consume los eventos de usuario y publica las notificaciones.
+//
+// La entrega es al menos una vez: el offset se confirma después de procesar el evento,
+// por lo que un reinicio puede reentregar mensajes ya procesados. Los duplicados se
+// absorben con el store de idempotencia (por Event.ID), el progreso por paso y el outbox.
+// No hay modo exactly-once con transacciones de Kafka: kafka-go no implementa el
+// protocolo transaccional (InitProducerId, AddOffsetsToTxn, EndTxn), y cambiar de cliente
+// solo para ese modo duplicaría el consumer y el producer.
 package kafka
 
 import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/andrew/orquestador-notificacion/internal/domain"
+	"github.com/andrew/orquestador-notificacion/internal/errs"
+	"github.com/andrew/orquestador-notificacion/internal/health"
 	"github.com/andrew/orquestador-notificacion/internal/logger"
 	"github.com/andrew/orquestador-notificacion/internal/processor"
 	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// defaultPublishBackoff son las esperas entre intentos de publicar un evento fallido en su
+// topic de reintento o en el DLQ, antes de detener el consumer
+var defaultPublishBackoff = []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second}
+
+const (
+	// minThrottlePause y maxThrottlePause acotan la pausa de un worker ante un error Throttled
+	minThrottlePause = time.Second
+	maxThrottlePause = time.Minute
+)
+
+// messageReader abstrae el kafka.Reader para poder sustituirlo en pruebas
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
 type Consumer struct {
-	reader    *kafka.Reader
-	processor *processor.Processor
-	logger    *logger.Logger
-	shutdown  chan struct{}
+	reader     messageReader
+	processor  *processor.Processor
+	logger     *logger.Logger
+	shutdown   chan struct{}
+	deadLetter *Producer
+	retry      *RetryTopics
+	orderBy    OrderingKey
+	offsets    *offsetTracker
+	commitMu   sync.Mutex
+	lag        *lagTracker
+	heartbeats *health.Heartbeats
+
+	// publishBackoff son las esperas entre intentos de publicar en un topic de reintento o DLQ
+	publishBackoff []time.Duration
+	stopOnce       sync.Once
+	failMu         sync.Mutex
+	failErr        error
 }
 
-func NewConsumer(cfg kafka.ReaderConfig, p *processor.Processor, log *logger.Logger) *Consumer {
+// ConsumerOption configura aspectos opcionales del Consumer
+type ConsumerOption func(*Consumer)
+
+// WithDeadLetter habilita el envío al topic de dead-letter tras agotar los intentos
+func WithDeadLetter(p *Producer) ConsumerOption {
+	return func(c *Consumer) {
+		c.deadLetter = p
+	}
+}
+
+// WithOrdering define cómo se agrupan los mensajes por worker (por defecto: clave del mensaje)
+func WithOrdering(k OrderingKey) ConsumerOption {
+	return func(c *Consumer) {
+		c.orderBy = k
+	}
+}
+
+// WithRetryTopics reprograma los eventos fallidos en la escalera de topics de reintento
+func WithRetryTopics(rt *RetryTopics) ConsumerOption {
+	return func(c *Consumer) {
+		c.retry = rt
+	}
+}
+
+func NewConsumer(cfg kafka.ReaderConfig, p *processor.Processor, log *logger.Logger, opts ...ConsumerOption) *Consumer {
 	// Configuración mejorada del Reader
 	cfg.MaxWait = 10 * time.Second
 	cfg.ReadBackoffMin = 100 * time.Millisecond
@@ -28,25 +100,89 @@ func NewConsumer(cfg kafka.ReaderConfig, p *processor.Processor, log *logger.Log
 	cfg.HeartbeatInterval = 3 * time.Second
 	cfg.CommitInterval = 0 // Commit manual para mejor control
 
-	r := kafka.NewReader(cfg)
-	return &Consumer{
-		reader:    r,
-		processor: p,
-		logger:    log,
-		shutdown:  make(chan struct{}),
+	return newConsumer(kafka.NewReader(cfg), cfg.Topic, p, log, opts...)
+}
+
+func newConsumer(r messageReader, topic string, p *processor.Processor, log *logger.Logger, opts ...ConsumerOption) *Consumer {
+	c := &Consumer{
+		reader:     r,
+		processor:  p,
+		logger:     log,
+		shutdown:   make(chan struct{}),
+		orderBy:    OrderByKey,
+		offsets:    newOffsetTracker(),
+		lag:        newLagTracker(topic),
+		heartbeats: health.NewHeartbeats(defaultWorkerTimeout),
+
+		publishBackoff: defaultPublishBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
+// Start lanza un único fetcher que reparte los mensajes entre workers según su clave de orden.
+// Los mensajes con la misma clave siempre van al mismo worker, preservando su orden.
 func (c *Consumer) Start(ctx context.Context, workers int) {
-	for i := 0; i < workers; i++ {
-		go c.worker(ctx, i)
+	if workers < 1 {
+		workers = 1
+	}
+	queues := make([]chan kafka.Message, workers)
+	for i := range queues {
+		queues[i] = make(chan kafka.Message, workerQueueSize)
+		go c.worker(ctx, i, queues[i])
+	}
+	go c.dispatch(ctx, queues)
+	go c.watchLag(ctx)
+}
+
+// dispatch es el único lector del kafka.Reader; registra cada offset y lo entrega a su worker
+func (c *Consumer) dispatch(ctx context.Context, queues []chan kafka.Message) {
+	c.heartbeats.Start(dispatcherName)
+	defer func() {
+		c.heartbeats.Exit(dispatcherName, c.stopping(ctx))
+		for _, q := range queues {
+			close(q)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Info("Dispatcher deteniéndose por cancelación de contexto", nil)
+			return
+		case <-c.shutdown:
+			c.logger.Info("Dispatcher deteniéndose por señal de apagado", nil)
+			return
+		default:
+		}
+
+		m, ok := c.fetchMessage(ctx)
+		if !ok {
+			continue
+		}
+
+		c.offsets.track(m)
+		c.lag.fetched(m, time.Now())
+		q := queues[workerFor(c.orderBy, m, len(queues))]
+		select {
+		case q <- m:
+		case <-ctx.Done():
+			return
+		case <-c.shutdown:
+			return
+		}
 	}
 }
 
-func (c *Consumer) worker(ctx context.Context, id int) {
+func (c *Consumer) worker(ctx context.Context, id int, queue <-chan kafka.Message) {
 	c.logger.Info("Iniciando worker de consumer de Kafka", map[string]interface{}{
 		"worker_id": id,
 	})
+	name := workerName(id)
+	c.heartbeats.Start(name)
+	defer func() { c.heartbeats.Exit(name, c.stopping(ctx)) }()
 
 	for {
 		select {
@@ -60,107 +196,361 @@ func (c *Consumer) worker(ctx context.Context, id int) {
 				"worker_id": id,
 			})
 			return
-		default:
-			c.processMessage(ctx, id)
+		case m, ok := <-queue:
+			if !ok {
+				return
+			}
+			c.heartbeats.Busy(name)
+			c.processMessage(ctx, id, m)
+			c.heartbeats.Idle(name)
 		}
 	}
 }
 
-func (c *Consumer) processMessage(ctx context.Context, workerID int) {
+// fetchMessage obtiene el siguiente mensaje; retorna false si no hubo mensaje que despachar
+func (c *Consumer) fetchMessage(ctx context.Context) (kafka.Message, bool) {
 	// Usar un contexto con timeout para evitar bloqueos eternos
 	msgCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
 	m, err := c.reader.FetchMessage(msgCtx)
 	if err != nil {
+		// Los errores de contexto se revisan primero: DeadlineExceeded también es un net.Error
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || strings.Contains(err.Error(), "context canceled") {
+			return kafka.Message{}, false
+		}
+
 		if isTransientError(err) {
 			c.logger.Warn("Error transitorio, se reintentará", map[string]interface{}{
-				"worker_id": workerID,
-				"error":     err.Error(),
+				"error": err.Error(),
 			})
 			time.Sleep(2 * time.Second)
-			return
-		}
-
-		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || strings.Contains(err.Error(), "context canceled") {
-			return
+			return kafka.Message{}, false
 		}
 
 		c.logger.Error("Fallo al obtener mensaje de Kafka", map[string]interface{}{
-			"worker_id": workerID,
-			"error":     err.Error(),
+			"error": err.Error(),
 		})
-		return
+		return kafka.Message{}, false
 	}
+	return m, true
+}
+
+func (c *Consumer) processMessage(ctx context.Context, workerID int, m kafka.Message) {
+	// El span continúa la traza del servicio que publicó el evento (header traceparent)
+	ctx, span := tracer.Start(extractTrace(ctx, m), "process "+m.Topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination", m.Topic),
+			attribute.Int("messaging.kafka.partition", m.Partition),
+			attribute.Int64("messaging.kafka.offset", m.Offset),
+		))
+	defer span.End()
 
 	var e domain.Event
 	if err := json.Unmarshal(m.Value, &e); err != nil {
-		c.logger.Error("JSON de evento inválido", map[string]interface{}{
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		c.logger.WithContext(ctx).Error("JSON de evento inválido", map[string]interface{}{
 			"worker_id": workerID,
 			"error":     err.Error(),
 			"raw":       string(m.Value),
 		})
+		eventsConsumed.WithLabelValues(m.Topic, invalidEventType).Inc()
 
-		// Commit para evitar procesar repetidamente mensajes inválidos
-		if err := c.reader.CommitMessages(ctx, m); err != nil {
-			c.logger.Error("Fallo al hacer commit después de mensaje inválido", map[string]interface{}{
-				"worker_id": workerID,
-				"error":     err.Error(),
-			})
-		}
+		// Reintentar no lo corrige: va directo al DLQ con el error de decodificación, que
+		// confirma el offset para no procesarlo repetidamente
+		var failure failureRecord
+		failure.record(errs.NewPermanent(fmt.Errorf("decodificar evento: %w", err)), time.Now())
+		c.sendToDeadLetter(ctx, workerID, m, &domain.Event{Type: invalidEventType}, failure)
 		return
 	}
 
-	c.logger.Info("Procesando evento", map[string]interface{}{
-		"worker_id": workerID,
+	// Los logs del evento, sus handlers y notificaciones comparten el correlation ID
+	ctx = extractCorrelation(ctx, m, &e)
+	c.logger.WithContext(ctx).Info("Procesando evento", map[string]interface{}{
+		"worker_id":  workerID,
 		"event_type": e.Type,
 		"event_id":   e.ID,
 	})
+	eventsConsumed.WithLabelValues(m.Topic, c.metricType(e.Type)).Inc()
+	span.SetAttributes(attribute.String("event.type", e.Type), attribute.String("event.id", e.ID))
 
-	// Procesar el evento
-	if err := c.processor.Process(ctx, &e); err != nil {
-		c.logger.Error("Fallo al procesar evento", map[string]interface{}{
-			"worker_id": workerID,
+	// Los mensajes de los topics de reintento esperan hasta su marca not-before
+	if !c.waitNotBefore(ctx, workerID, m) {
+		return
+	}
+
+	// Un error Throttled pausa el worker el tiempo pedido y vuelve a procesar el evento
+	for {
+		err := c.processor.Process(ctx, &e)
+		if err == nil {
+			break
+		}
+
+		if wait, ok := throttlePause(err); ok {
+			c.logger.WithContext(ctx).Warn("Evento limitado por tasa, se pausa el worker", map[string]interface{}{
+				"worker_id":  workerID,
+				"error":      err.Error(),
+				"event_type": e.Type,
+				"event_id":   e.ID,
+				"pause":      wait.String(),
+			})
+			eventRetries.WithLabelValues(m.Topic, c.metricType(e.Type), "throttled").Inc()
+			if !c.pause(ctx, workerID, wait) {
+				// No commit: el mensaje se volverá a entregar tras reiniciar
+				return
+			}
+			continue
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		failure := failureFromHeaders(m)
+		failure.record(err, time.Now())
+
+		c.logger.WithContext(ctx).Error("Fallo al procesar evento", map[string]interface{}{
+			"worker_id":  workerID,
 			"error":      err.Error(),
 			"event_type": e.Type,
 			"event_id":   e.ID,
+			"attempt":    failure.attempts,
+			"kind":       errs.KindOf(err).String(),
 		})
 
-		// No commit para reintentar más tarde
-		time.Sleep(5 * time.Second)
+		c.handleFailure(ctx, workerID, m, &e, failure)
 		return
 	}
 
 	// Commit después de procesamiento exitoso
-	if err := c.reader.CommitMessages(ctx, m); err != nil {
-		c.logger.Error("Fallo al hacer commit del mensaje", map[string]interface{}{
+	if c.commit(ctx, workerID, m) {
+		c.logger.WithContext(ctx).Info("Mensaje confirmado exitosamente", map[string]interface{}{
 			"worker_id": workerID,
-			"error":     err.Error(),
+			"event_id":  e.ID,
+		})
+	}
+}
+
+// waitNotBefore bloquea hasta la marca not-before del mensaje; retorna false si el contexto termina
+func (c *Consumer) waitNotBefore(ctx context.Context, workerID int, m kafka.Message) bool {
+	due, ok := notBefore(m)
+	if !ok {
+		return true
+	}
+	// No commit si se interrumpe: el mensaje se volverá a entregar tras reiniciar
+	return c.pause(ctx, workerID, time.Until(due))
+}
+
+// sleep espera d; retorna false si el contexto termina o el consumer se cierra antes
+func (c *Consumer) sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-c.shutdown:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// throttlePause retorna cuánto pausar el worker ante un error Throttled. Las esperas
+// mayores a maxThrottlePause se derivan a los topics de reintento para no bloquear la partición.
+func throttlePause(err error) (time.Duration, bool) {
+	if errs.KindOf(err) != errs.Throttled {
+		return 0, false
+	}
+	wait, _ := errs.RetryAfter(err)
+	if wait > maxThrottlePause {
+		return 0, false
+	}
+	if wait < minThrottlePause {
+		wait = minThrottlePause
+	}
+	return wait, true
+}
+
+// handleFailure reprograma el evento en el siguiente escalón de reintento o lo envía al DLQ
+// al agotar la política de su tipo. En ambos casos se confirma el offset para no bloquear la partición.
+// Si la publicación falla tras reintentarla, el consumer se detiene (ver fail).
+func (c *Consumer) handleFailure(ctx context.Context, workerID int, m kafka.Message, e *domain.Event, failure failureRecord) {
+	policy := c.processor.RetryPolicy(e.Type)
+
+	// Los errores permanentes (ej: payload o destinatario inválido) no se corrigen reintentando
+	if errs.IsPermanent(failure.err) {
+		c.sendToDeadLetter(ctx, workerID, m, e, failure)
+		return
+	}
+
+	if c.retry != nil && failure.attempts <= len(policy.Delays) {
+		delay := policy.Delays[failure.attempts-1]
+		err := c.publishWithRetry(ctx, workerID, func() error {
+			return c.retry.Schedule(ctx, m, delay, failure, time.Now())
+		})
+		if err != nil {
+			c.logger.WithContext(ctx).Error("Fallo al reprogramar evento en topic de reintento", map[string]interface{}{
+				"worker_id":  workerID,
+				"error":      err.Error(),
+				"event_type": e.Type,
+				"event_id":   e.ID,
+				"delay":      delay.String(),
+			})
+			c.fail(ctx, m, fmt.Errorf("no se pudo reprogramar el evento %s: %w", e.ID, err))
+			return
+		}
+		c.logger.WithContext(ctx).Warn("Evento reprogramado en topic de reintento", map[string]interface{}{
+			"worker_id":    workerID,
+			"event_type":   e.Type,
+			"event_id":     e.ID,
+			"attempt":      failure.attempts,
+			"max_attempts": policy.MaxAttempts(),
+			"delay":        delay.String(),
+		})
+		eventRetries.WithLabelValues(m.Topic, c.metricType(e.Type), "retry_topic").Inc()
+		c.commit(ctx, workerID, m)
+		return
+	}
+
+	c.sendToDeadLetter(ctx, workerID, m, e, failure)
+}
+
+// sendToDeadLetter publica el mensaje original en el DLQ y confirma el offset.
+// Si la publicación falla tras reintentarla no se hace commit, para no perder el mensaje,
+// y el consumer se detiene (ver fail).
+func (c *Consumer) sendToDeadLetter(ctx context.Context, workerID int, m kafka.Message, e *domain.Event, failure failureRecord) {
+	if c.deadLetter == nil {
+		c.logger.WithContext(ctx).Error("Intentos agotados sin DLQ configurado, se descarta el evento", map[string]interface{}{
+			"worker_id":  workerID,
+			"event_type": e.Type,
+			"event_id":   e.ID,
+			"attempts":   failure.attempts,
 		})
 	} else {
-		c.logger.Info("Mensaje confirmado exitosamente", map[string]interface{}{
+		dlqMsg := buildDeadLetterMessage(m, failure)
+		err := c.publishWithRetry(ctx, workerID, func() error {
+			return c.deadLetter.SendMessage(ctx, dlqMsg)
+		})
+		if err != nil {
+			c.logger.WithContext(ctx).Error("Fallo al publicar evento en DLQ", map[string]interface{}{
+				"worker_id":  workerID,
+				"error":      err.Error(),
+				"event_type": e.Type,
+				"event_id":   e.ID,
+			})
+			c.fail(ctx, m, fmt.Errorf("no se pudo publicar el evento %s en el DLQ: %w", e.ID, err))
+			return
+		}
+		c.logger.WithContext(ctx).Warn("Evento enviado a DLQ", map[string]interface{}{
+			"worker_id":  workerID,
+			"event_type": e.Type,
+			"event_id":   e.ID,
+			"attempts":   failure.attempts,
+			"handler":    failure.handler,
+			"kind":       errs.KindOf(failure.err).String(),
+		})
+		deadLetters.WithLabelValues(m.Topic, c.metricType(e.Type), errs.KindOf(failure.err).String()).Inc()
+	}
+
+	c.commit(ctx, workerID, m)
+}
+
+// commit marca el mensaje como procesado y confirma el mayor offset contiguo de su partición.
+// Retorna false si el commit a Kafka falló.
+func (c *Consumer) commit(ctx context.Context, workerID int, m kafka.Message) bool {
+	c.commitMu.Lock()
+	defer c.commitMu.Unlock()
+
+	upTo, ok := c.offsets.ack(m)
+	if !ok {
+		// Hay offsets anteriores en proceso; se confirmará cuando terminen
+		return true
+	}
+	if err := c.reader.CommitMessages(ctx, upTo); err != nil {
+		c.logger.WithContext(ctx).Error("Fallo al hacer commit del mensaje", map[string]interface{}{
 			"worker_id": workerID,
-			"event_id":  e.ID,
+			"error":     err.Error(),
+			"partition": upTo.Partition,
+			"offset":    upTo.Offset,
 		})
+		commitFailures.WithLabelValues(upTo.Topic).Inc()
+		return false
 	}
+	c.lag.committed(upTo, time.Now())
+	return true
 }
 
-// isTransientError identifica errores transitorios que merecen reintento
+// isTransientError identifica errores de red o del broker que merecen reintento
+// (conexiones caídas, timeouts, errores de Kafka marcados como temporales)
 func isTransientError(err error) bool {
 	if err == nil {
 		return false
 	}
+	var netErr net.Error
+	var temporary interface{ Temporary() bool }
+	return errors.Is(err, io.ErrNoProgress) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.As(err, &netErr) ||
+		(errors.As(err, &temporary) && temporary.Temporary())
+}
 
-	errorMsg := err.Error()
-	return strings.Contains(errorMsg, "multiple Read calls return no data or error") ||
-		strings.Contains(errorMsg, "connection reset by peer") ||
-		strings.Contains(errorMsg, "i/o timeout") ||
-		strings.Contains(errorMsg, "broker not available") ||
-		strings.Contains(errorMsg, "network error")
+// publishWithRetry reintenta publish con las esperas de publishBackoff; retorna el último
+// error si ningún intento tuvo éxito o el consumer se detiene mientras espera
+func (c *Consumer) publishWithRetry(ctx context.Context, workerID int, publish func() error) error {
+	err := publish()
+	for _, wait := range c.publishBackoff {
+		if err == nil || !c.pause(ctx, workerID, wait) {
+			break
+		}
+		err = publish()
+	}
+	return err
+}
+
+// fail detiene el consumer cuando un mensaje no puede confirmarse ni reprogramarse: su
+// offset sin confirmar bloquearía en silencio los commits posteriores de la partición.
+// Err expone el error para que el proceso se reinicie y el mensaje se vuelva a entregar.
+// Durante un apagado no hace nada: el mensaje se reentrega igual.
+func (c *Consumer) fail(ctx context.Context, m kafka.Message, err error) {
+	if c.stopping(ctx) {
+		return
+	}
+	c.failMu.Lock()
+	if c.failErr == nil {
+		c.failErr = fmt.Errorf("partición %d detenida en el offset %d: %w", m.Partition, m.Offset, err)
+	}
+	c.failMu.Unlock()
+
+	c.logger.WithContext(ctx).Error("Consumer detenido: offset sin confirmar bloquea la partición", map[string]interface{}{
+		"topic":     m.Topic,
+		"partition": m.Partition,
+		"offset":    m.Offset,
+		"error":     err.Error(),
+	})
+	c.stop()
+}
+
+// Err retorna el error que detuvo el consumer, o nil si sigue activo
+func (c *Consumer) Err() error {
+	c.failMu.Lock()
+	defer c.failMu.Unlock()
+	return c.failErr
 }
 
 func (c *Consumer) Close() error {
 	c.logger.Info("Cerrando consumer de Kafka", nil)
-	close(c.shutdown)
+	c.stop()
 	return c.reader.Close()
 }
+
+// stop detiene el dispatcher y los workers; puede llamarse más de una vez
+func (c *Consumer) stop() {
+	c.stopOnce.Do(func() { close(c.shutdown) })
+}
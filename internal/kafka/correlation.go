@@ -0,0 +1,41 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/andrew/orquestador-notificacion/internal/domain"
+	"github.com/andrew/orquestador-notificacion/internal/logger"
+	"github.com/segmentio/kafka-go"
+)
+
+// HeaderCorrelationID une los mensajes de un mismo flujo entre servicios
+const HeaderCorrelationID = "x-correlation-id"
+
+// InjectContext agrega a los headers la traza y el correlation ID activos en ctx
+func InjectContext(ctx context.Context, headers []kafka.Header) []kafka.Header {
+	return InjectCorrelation(ctx, InjectTrace(ctx, headers))
+}
+
+// InjectCorrelation agrega a los headers el correlation ID de ctx, reemplazando el que
+// trajera el mensaje. Sin correlation ID no los modifica.
+func InjectCorrelation(ctx context.Context, headers []kafka.Header) []kafka.Header {
+	id := logger.CorrelationID(ctx)
+	if id == "" {
+		return headers
+	}
+	headers = withoutHeaders(headers, HeaderCorrelationID)
+	return append(headers, kafka.Header{Key: HeaderCorrelationID, Value: []byte(id)})
+}
+
+// extractCorrelation registra en ctx el correlation ID del mensaje (header, campo del
+// evento o, si no viene ninguno, el ID del evento) y el ID del evento
+func extractCorrelation(ctx context.Context, m kafka.Message, e *domain.Event) context.Context {
+	id := headerValue(m.Headers, HeaderCorrelationID)
+	if id == "" {
+		id = e.CorrelationID
+	}
+	if id == "" {
+		id = e.ID
+	}
+	return logger.WithEventID(logger.WithCorrelationID(ctx, id), e.ID)
+}
@@ -0,0 +1,83 @@
+package kafka
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/errs"
+	"github.com/andrew/orquestador-notificacion/internal/processor"
+	"github.com/segmentio/kafka-go"
+)
+
+// Headers agregados a los mensajes publicados en el topic de dead-letter
+const (
+	HeaderDLQError          = "x-dlq-error"
+	HeaderDLQErrorKind      = "x-dlq-error-kind"
+	HeaderDLQAttempts       = "x-dlq-attempts"
+	HeaderDLQHandler        = "x-dlq-handler"
+	HeaderDLQFirstFailure   = "x-dlq-first-failure"
+	HeaderDLQLastFailure    = "x-dlq-last-failure"
+	HeaderDLQOriginalTopic  = "x-dlq-original-topic"
+	HeaderDLQOriginalPart   = "x-dlq-original-partition"
+	HeaderDLQOriginalOffset = "x-dlq-original-offset"
+)
+
+// failureRecord acumula los metadatos de fallo de un mensaje
+type failureRecord struct {
+	attempts     int
+	firstFailure time.Time
+	lastFailure  time.Time
+	handler      string
+	err          error
+}
+
+func (f *failureRecord) record(err error, now time.Time) {
+	if f.attempts == 0 {
+		f.firstFailure = now
+	}
+	f.attempts++
+	f.lastFailure = now
+	f.err = err
+
+	var he *processor.HandlerError
+	if errors.As(err, &he) {
+		f.handler = he.Handler
+	}
+}
+
+func (f *failureRecord) errorMessage() string {
+	if f.err == nil {
+		return ""
+	}
+	return f.err.Error()
+}
+
+// buildDeadLetterMessage copia el mensaje original y agrega los metadatos de fallo
+func buildDeadLetterMessage(m kafka.Message, f failureRecord) kafka.Message {
+	headers := make([]kafka.Header, 0, len(m.Headers)+9)
+	headers = append(headers, m.Headers...)
+
+	originalTopic := headerValue(m.Headers, HeaderRetryOriginalTopic)
+	if originalTopic == "" {
+		originalTopic = m.Topic
+	}
+
+	headers = append(headers,
+		kafka.Header{Key: HeaderDLQError, Value: []byte(f.errorMessage())},
+		kafka.Header{Key: HeaderDLQErrorKind, Value: []byte(errs.KindOf(f.err).String())},
+		kafka.Header{Key: HeaderDLQAttempts, Value: []byte(strconv.Itoa(f.attempts))},
+		kafka.Header{Key: HeaderDLQHandler, Value: []byte(f.handler)},
+		kafka.Header{Key: HeaderDLQFirstFailure, Value: []byte(f.firstFailure.UTC().Format(time.RFC3339Nano))},
+		kafka.Header{Key: HeaderDLQLastFailure, Value: []byte(f.lastFailure.UTC().Format(time.RFC3339Nano))},
+		kafka.Header{Key: HeaderDLQOriginalTopic, Value: []byte(originalTopic)},
+		kafka.Header{Key: HeaderDLQOriginalPart, Value: []byte(strconv.Itoa(m.Partition))},
+		kafka.Header{Key: HeaderDLQOriginalOffset, Value: []byte(strconv.FormatInt(m.Offset, 10))},
+	)
+
+	return kafka.Message{
+		Key:     m.Key,
+		Value:   m.Value,
+		Headers: headers,
+	}
+}
@@ -0,0 +1,335 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/domain"
+	"github.com/andrew/orquestador-notificacion/internal/errs"
+	"github.com/andrew/orquestador-notificacion/internal/handler"
+	"github.com/andrew/orquestador-notificacion/internal/logger"
+	"github.com/andrew/orquestador-notificacion/internal/processor"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/segmentio/kafka-go"
+)
+
+// journal registra en orden las escrituras y commits de los fakes de un mismo test
+type journal struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (j *journal) add(entry string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = append(j.entries, entry)
+}
+
+func (j *journal) list() []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]string(nil), j.entries...)
+}
+
+// fakeWriter es un MessageWriter en memoria. err hace fallar todas las escrituras y
+// failures solo las primeras.
+type fakeWriter struct {
+	name     string
+	journal  *journal
+	mu       sync.Mutex
+	msgs     []kafka.Message
+	err      error
+	failures int
+	attempts int
+}
+
+func (w *fakeWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.attempts++
+	if w.err != nil {
+		return w.err
+	}
+	if w.failures > 0 {
+		w.failures--
+		return errors.New("leader not available")
+	}
+	w.msgs = append(w.msgs, msgs...)
+	if w.journal != nil {
+		w.journal.add("write:" + w.name)
+	}
+	return nil
+}
+
+func (w *fakeWriter) Close() error { return nil }
+
+func (w *fakeWriter) messages() []kafka.Message {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]kafka.Message(nil), w.msgs...)
+}
+
+// fakeReader es un messageReader que solo registra los commits
+type fakeReader struct {
+	journal *journal
+	mu      sync.Mutex
+	commits []kafka.Message
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *fakeReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commits = append(r.commits, msgs...)
+	for _, m := range msgs {
+		r.journal.add("commit:" + strconv.FormatInt(m.Offset, 10))
+	}
+	return nil
+}
+
+func (r *fakeReader) Close() error { return nil }
+
+func (r *fakeReader) committed() []kafka.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]kafka.Message(nil), r.commits...)
+}
+
+// failingHandler falla siempre con err
+type failingHandler struct {
+	eventType string
+	err       error
+}
+
+func (h *failingHandler) Handle(context.Context, *domain.Event) error { return h.err }
+func (h *failingHandler) Types() []string                             { return []string{h.eventType} }
+
+func newTestProcessor(h handler.EventHandler, policy handler.RetryPolicy) *processor.Processor {
+	reg := handler.NewRegistry()
+	reg.Register(h)
+	reg.SetDefaultRetryPolicy(policy)
+	return processor.NewProcessor(reg, logger.New("[Test]"))
+}
+
+func eventMessage(topic string, partition int, offset int64, headers ...kafka.Header) kafka.Message {
+	return kafka.Message{
+		Topic:     topic,
+		Partition: partition,
+		Offset:    offset,
+		Key:       []byte("user-1"),
+		Value:     []byte(`{"id":"evt-1","type":"USER_LOGIN","payload":{"id":1}}`),
+		Headers:   headers,
+	}
+}
+
+func TestSendToDeadLetter(t *testing.T) {
+	permanent := errs.NewPermanent(errors.New("destinatario inválido"))
+
+	tests := []struct {
+		name        string
+		msg         kafka.Message
+		wantTopic   string
+		wantAttempt string
+	}{
+		{
+			name:        "error permanente en el topic principal",
+			msg:         eventMessage("user-events", 3, 42),
+			wantTopic:   "user-events",
+			wantAttempt: "1",
+		},
+		{
+			name: "mensaje reprogramado conserva el topic original",
+			msg: eventMessage("user-events.retry.5m", 0, 7,
+				kafka.Header{Key: HeaderRetryOriginalTopic, Value: []byte("user-events")},
+				kafka.Header{Key: HeaderRetryAttempts, Value: []byte("2")}),
+			wantTopic:   "user-events",
+			wantAttempt: "3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := &journal{}
+			dlq := &fakeWriter{name: "dlq", journal: j}
+			reader := &fakeReader{journal: j}
+			p := newTestProcessor(&failingHandler{eventType: "USER_LOGIN", err: permanent}, handler.RetryPolicy{})
+			c := newConsumer(reader, tt.msg.Topic, p, logger.New("[Test]"),
+				WithDeadLetter(NewProducerWithWriter(dlq)))
+
+			c.offsets.track(tt.msg)
+			c.processMessage(context.Background(), 0, tt.msg)
+
+			sent := dlq.messages()
+			if len(sent) != 1 {
+				t.Fatalf("mensajes en DLQ = %d, se esperaba 1", len(sent))
+			}
+			got := sent[0]
+			if string(got.Value) != string(tt.msg.Value) || string(got.Key) != string(tt.msg.Key) {
+				t.Errorf("el DLQ no conserva key/value originales: %s/%s", got.Key, got.Value)
+			}
+
+			want := map[string]string{
+				HeaderDLQError:          "*kafka.failingHandler: " + permanent.Error(),
+				HeaderDLQErrorKind:      errs.Permanent.String(),
+				HeaderDLQAttempts:       tt.wantAttempt,
+				HeaderDLQHandler:        "*kafka.failingHandler",
+				HeaderDLQOriginalTopic:  tt.wantTopic,
+				HeaderDLQOriginalPart:   strconv.Itoa(tt.msg.Partition),
+				HeaderDLQOriginalOffset: strconv.FormatInt(tt.msg.Offset, 10),
+			}
+			for key, value := range want {
+				if v := headerValue(got.Headers, key); v != value {
+					t.Errorf("header %s = %q, se esperaba %q", key, v, value)
+				}
+			}
+			for _, key := range []string{HeaderDLQFirstFailure, HeaderDLQLastFailure} {
+				if _, err := time.Parse(time.RFC3339Nano, headerValue(got.Headers, key)); err != nil {
+					t.Errorf("header %s inválido: %v", key, err)
+				}
+			}
+
+			wantJournal := []string{"write:dlq", "commit:" + strconv.FormatInt(tt.msg.Offset, 10)}
+			if got := j.list(); !equalStrings(got, wantJournal) {
+				t.Errorf("secuencia = %v, se esperaba %v", got, wantJournal)
+			}
+		})
+	}
+}
+
+func TestInvalidEventGoesToDeadLetter(t *testing.T) {
+	j := &journal{}
+	dlq := &fakeWriter{name: "dlq", journal: j}
+	reader := &fakeReader{journal: j}
+	p := newTestProcessor(&failingHandler{eventType: "USER_LOGIN"}, handler.RetryPolicy{})
+	c := newConsumer(reader, "user-events", p, logger.New("[Test]"),
+		WithDeadLetter(NewProducerWithWriter(dlq)))
+
+	m := eventMessage("user-events", 1, 9)
+	m.Value = []byte(`{"id":"evt-1","type":`)
+	c.offsets.track(m)
+	c.processMessage(context.Background(), 0, m)
+
+	sent := dlq.messages()
+	if len(sent) != 1 || string(sent[0].Value) != string(m.Value) {
+		t.Fatalf("mensajes en DLQ = %v, se esperaba el mensaje original", sent)
+	}
+	if v := headerValue(sent[0].Headers, HeaderDLQErrorKind); v != errs.Permanent.String() {
+		t.Errorf("header %s = %q, se esperaba %q", HeaderDLQErrorKind, v, errs.Permanent.String())
+	}
+	if v := headerValue(sent[0].Headers, HeaderDLQError); !strings.Contains(v, "decodificar evento") {
+		t.Errorf("header %s = %q, se esperaba el error de decodificación", HeaderDLQError, v)
+	}
+	if got, want := j.list(), []string{"write:dlq", "commit:9"}; !equalStrings(got, want) {
+		t.Errorf("secuencia = %v, se esperaba %v", got, want)
+	}
+}
+
+func TestMetricTypeBoundsCardinality(t *testing.T) {
+	p := newTestProcessor(&failingHandler{eventType: "USER_LOGIN"}, handler.RetryPolicy{})
+	c := newConsumer(&fakeReader{journal: &journal{}}, "user-events", p, logger.New("[Test]"))
+
+	for eventType, want := range map[string]string{
+		"USER_LOGIN":      "USER_LOGIN",
+		"USER_LOGIN_9f3a": otherEventType,
+		"":                otherEventType,
+		invalidEventType:  invalidEventType,
+	} {
+		if got := c.metricType(eventType); got != want {
+			t.Errorf("metricType(%q) = %q, se esperaba %q", eventType, got, want)
+		}
+	}
+
+	m := eventMessage("metrics-test", 0, 1)
+	m.Value = []byte(`{"id":"evt-2","type":"RANDOM_123","payload":{}}`)
+	c.offsets.track(m)
+	c.processMessage(context.Background(), 0, m)
+	if got := testutil.ToFloat64(eventsConsumed.WithLabelValues("metrics-test", otherEventType)); got != 1 {
+		t.Errorf("eventos consumidos con type=other = %v, se esperaba 1", got)
+	}
+}
+
+func TestSendToDeadLetterPublishFailure(t *testing.T) {
+	tests := []struct {
+		name         string
+		writer       *fakeWriter
+		wantAttempts int
+		wantCommit   bool
+	}{
+		{
+			name:         "falla transitoria: se reintenta y confirma",
+			writer:       &fakeWriter{name: "dlq", failures: 2},
+			wantAttempts: 3,
+			wantCommit:   true,
+		},
+		{
+			name:         "falla persistente: sin commit y consumer detenido",
+			writer:       &fakeWriter{name: "dlq", err: errors.New("broker caído")},
+			wantAttempts: 4,
+			wantCommit:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := &journal{}
+			tt.writer.journal = j
+			reader := &fakeReader{journal: j}
+			p := newTestProcessor(&failingHandler{
+				eventType: "USER_LOGIN",
+				err:       errs.NewPermanent(errors.New("payload inválido")),
+			}, handler.RetryPolicy{})
+			c := newConsumer(reader, "user-events", p, logger.New("[Test]"),
+				WithDeadLetter(NewProducerWithWriter(tt.writer)))
+			c.publishBackoff = []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}
+
+			m := eventMessage("user-events", 0, 5)
+			c.offsets.track(m)
+			c.processMessage(context.Background(), 0, m)
+
+			if tt.writer.attempts != tt.wantAttempts {
+				t.Errorf("intentos de publicación = %d, se esperaban %d", tt.writer.attempts, tt.wantAttempts)
+			}
+			commits := reader.committed()
+			if committed := len(commits) > 0; committed != tt.wantCommit {
+				t.Fatalf("commit = %v, se esperaba %v", committed, tt.wantCommit)
+			}
+
+			_, err := c.Liveness(context.Background())
+			if tt.wantCommit {
+				if c.Err() != nil {
+					t.Errorf("consumer detenido tras una falla transitoria: %v", c.Err())
+				}
+				return
+			}
+			if c.Err() == nil || err == nil {
+				t.Fatal("el consumer debería quedar detenido y el check de liveness fallar")
+			}
+			select {
+			case <-c.shutdown:
+			default:
+				t.Error("el consumer no se detuvo")
+			}
+		})
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
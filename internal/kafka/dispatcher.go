@@ -0,0 +1,32 @@
+package kafka
+
+import (
+	"hash/fnv"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// workerQueueSize es la cantidad de mensajes que el dispatcher deja en cola por worker
+const workerQueueSize = 16
+
+// OrderingKey define qué se preserva en orden al repartir mensajes entre workers
+type OrderingKey int
+
+const (
+	// OrderByKey agrupa por la clave del mensaje (ej: id de usuario); sin clave usa la partición
+	OrderByKey OrderingKey = iota
+	// OrderByPartition procesa cada partición en un único worker
+	OrderByPartition
+)
+
+// workerFor elige el worker de un mensaje de forma determinista
+func workerFor(orderBy OrderingKey, m kafka.Message, workers int) int {
+	h := fnv.New32a()
+	if orderBy == OrderByKey && len(m.Key) > 0 {
+		h.Write(m.Key)
+	} else {
+		h.Write([]byte(m.Topic))
+		h.Write([]byte{byte(m.Partition >> 24), byte(m.Partition >> 16), byte(m.Partition >> 8), byte(m.Partition)})
+	}
+	return int(h.Sum32() % uint32(workers))
+}
@@ -0,0 +1,152 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/health"
+	"github.com/segmentio/kafka-go"
+)
+
+// defaultWorkerTimeout es cuánto puede tardar un worker con un mensaje antes de
+// considerarse trabado
+const defaultWorkerTimeout = 5 * time.Minute
+
+// dispatcherName identifica al fetcher en los heartbeats del consumer
+const dispatcherName = "dispatcher"
+
+// WithWorkerTimeout define cuánto puede tardar un worker en procesar un mensaje antes de
+// que el check de liveness lo considere trabado (las pausas deliberadas no cuentan)
+func WithWorkerTimeout(d time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		c.heartbeats = health.NewHeartbeats(d)
+	}
+}
+
+func workerName(id int) string {
+	return "worker-" + strconv.Itoa(id)
+}
+
+// stopping indica si el consumer se está apagando (fin ordenado de sus goroutines)
+func (c *Consumer) stopping(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+	select {
+	case <-c.shutdown:
+		return true
+	default:
+		return false
+	}
+}
+
+// pause espera d sin contar como trabajo en curso para el check de liveness
+func (c *Consumer) pause(ctx context.Context, workerID int, d time.Duration) bool {
+	name := workerName(workerID)
+	c.heartbeats.Idle(name)
+	defer c.heartbeats.Busy(name)
+	return c.sleep(ctx, d)
+}
+
+// Liveness es un health.Check: falla si algún worker está trabado o terminó inesperadamente,
+// o si el consumer se detuvo por un offset que no pudo confirmar
+func (c *Consumer) Liveness(ctx context.Context) (map[string]interface{}, error) {
+	data, err := c.heartbeats.Check(ctx)
+	data["topic"] = c.lag.topic
+	if failErr := c.Err(); failErr != nil {
+		data["failed"] = failErr.Error()
+		return data, failErr
+	}
+	return data, err
+}
+
+// LagCheck es un health.Check de readiness: falla si el lag superó el límite de forma sostenida
+func (c *Consumer) LagCheck(_ context.Context) (map[string]interface{}, error) {
+	report := c.Lag()
+	data := map[string]interface{}{
+		"topic":     report.Topic,
+		"total_lag": report.TotalLag,
+		"threshold": report.Threshold,
+	}
+	if report.ExceededSince != nil {
+		data["exceeded_since"] = report.ExceededSince.Format(time.RFC3339)
+	}
+	if !report.Healthy {
+		return data, fmt.Errorf("lag %d sobre el límite %d durante más de %s", report.TotalLag, report.Threshold, report.Sustained)
+	}
+	return data, nil
+}
+
+// BrokerCheck es un health.Check de readiness que verifica que algún broker responda
+func BrokerCheck(brokers []string) health.Check {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		data := map[string]interface{}{"brokers": brokers}
+		var lastErr error
+		for _, addr := range brokers {
+			conn, err := kafka.DialContext(ctx, "tcp", addr)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			_, err = conn.Brokers()
+			conn.Close()
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			data["reachable"] = addr
+			return data, nil
+		}
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no hay brokers configurados")
+		}
+		return data, fmt.Errorf("ningún broker responde: %w", lastErr)
+	}
+}
+
+// writeStatus registra el resultado de las escrituras del producer
+type writeStatus struct {
+	mu          sync.Mutex
+	lastSuccess time.Time
+	lastFailure time.Time
+	lastErr     error
+	failures    int // fallos consecutivos
+}
+
+func (s *writeStatus) record(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		s.lastSuccess = time.Now()
+		s.failures = 0
+		return
+	}
+	// La cancelación del llamador no dice nada del broker
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	s.lastFailure = time.Now()
+	s.lastErr = err
+	s.failures++
+}
+
+// Check es un health.Check de readiness: falla si la última escritura del producer falló
+func (p *Producer) Check(_ context.Context) (map[string]interface{}, error) {
+	s := &p.status
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := map[string]interface{}{"consecutive_failures": s.failures}
+	if !s.lastSuccess.IsZero() {
+		data["last_success"] = s.lastSuccess.Format(time.RFC3339)
+	}
+	if s.failures > 0 {
+		data["last_failure"] = s.lastFailure.Format(time.RFC3339)
+		return data, fmt.Errorf("última escritura fallida: %w", s.lastErr)
+	}
+	return data, nil
+}
@@ -0,0 +1,227 @@
+package kafka
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/metrics"
+	"github.com/segmentio/kafka-go"
+)
+
+// lagStatsInterval es cada cuánto se consultan las estadísticas del reader
+const lagStatsInterval = 15 * time.Second
+
+// Métricas de lag y asignación de particiones
+var (
+	consumerLag = metrics.NewGauge("orchestrator_consumer_lag",
+		"Mensajes pendientes por partición (high-water mark menos offset actual)", "topic", "partition")
+	consumerOffset = metrics.NewGauge("orchestrator_consumer_offset",
+		"Próximo offset a confirmar por partición", "topic", "partition")
+	consumerHighWaterMark = metrics.NewGauge("orchestrator_consumer_high_water_mark",
+		"High-water mark de la partición visto en el último fetch", "topic", "partition")
+	assignedPartitions = metrics.NewGauge("orchestrator_consumer_assigned_partitions",
+		"Particiones asignadas al consumer con mensajes recibidos desde el último rebalanceo", "topic")
+	assignmentChanges = metrics.NewCounter("orchestrator_consumer_assignment_changes_total",
+		"Cambios de asignación de particiones (assigned o revoked)", "topic", "change")
+	consumerRebalances = metrics.NewCounter("orchestrator_consumer_rebalances_total",
+		"Rebalanceos del grupo de consumidores informados por el reader", "topic")
+)
+
+// PartitionLag es el estado de consumo de una partición asignada
+type PartitionLag struct {
+	Partition     int       `json:"partition"`
+	Offset        int64     `json:"offset"`
+	HighWaterMark int64     `json:"high_water_mark"`
+	Lag           int64     `json:"lag"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// LagReport resume el lag de un consumer para el endpoint de administración y el readiness
+type LagReport struct {
+	Topic      string         `json:"topic"`
+	Partitions []PartitionLag `json:"partitions"`
+	TotalLag   int64          `json:"total_lag"`
+	Rebalances int64          `json:"rebalances"`
+	// Threshold y Sustained son el límite configurado (0 = sin límite)
+	Threshold     int64      `json:"threshold,omitempty"`
+	Sustained     string     `json:"sustained,omitempty"`
+	ExceededSince *time.Time `json:"exceeded_since,omitempty"`
+	Healthy       bool       `json:"healthy"`
+}
+
+// statsReader es implementado por kafka.Reader. Los contadores de Stats son deltas desde
+// la llamada anterior, por lo que solo refreshStats debe consultarlo.
+type statsReader interface {
+	Stats() kafka.ReaderStats
+}
+
+// lagTracker mantiene el offset y el high-water mark de cada partición asignada.
+// El offset es el siguiente al último confirmado o, antes del primer commit, el del
+// primer mensaje recibido.
+type lagTracker struct {
+	mu            sync.Mutex
+	topic         string
+	partitions    map[int]*PartitionLag
+	rebalances    int64
+	threshold     int64
+	sustained     time.Duration
+	exceededSince time.Time
+}
+
+func newLagTracker(topic string) *lagTracker {
+	return &lagTracker{topic: topic, partitions: make(map[int]*PartitionLag)}
+}
+
+// WithLagThreshold hace que el consumer deje de estar listo (readiness) cuando el lag total
+// supera max mensajes durante al menos sustained
+func WithLagThreshold(max int64, sustained time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		c.lag.threshold = max
+		c.lag.sustained = sustained
+	}
+}
+
+// fetched actualiza el high-water mark de la partición con un mensaje recién obtenido
+func (t *lagTracker) fetched(m kafka.Message, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[m.Partition]
+	if !ok {
+		p = &PartitionLag{Partition: m.Partition, Offset: m.Offset}
+		t.partitions[m.Partition] = p
+		assignmentChanges.WithLabelValues(t.topic, "assigned").Inc()
+		assignedPartitions.WithLabelValues(t.topic).Set(float64(len(t.partitions)))
+	}
+	p.HighWaterMark = m.HighWaterMark
+	t.update(p, now)
+}
+
+// committed registra el offset confirmado de la partición
+func (t *lagTracker) committed(m kafka.Message, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[m.Partition]
+	if !ok {
+		return
+	}
+	p.Offset = m.Offset + 1
+	t.update(p, now)
+}
+
+// update recalcula el lag de la partición; requiere mu tomado
+func (t *lagTracker) update(p *PartitionLag, now time.Time) {
+	p.Lag = p.HighWaterMark - p.Offset
+	if p.Lag < 0 {
+		p.Lag = 0
+	}
+	p.UpdatedAt = now
+	partition := strconv.Itoa(p.Partition)
+	consumerLag.WithLabelValues(t.topic, partition).Set(float64(p.Lag))
+	consumerOffset.WithLabelValues(t.topic, partition).Set(float64(p.Offset))
+	consumerHighWaterMark.WithLabelValues(t.topic, partition).Set(float64(p.HighWaterMark))
+	t.evaluate(now)
+}
+
+// rebalanced registra los rebalanceos nuevos informados por el reader. Tras un rebalanceo
+// las particiones se revocan y se vuelven a registrar a medida que llegan mensajes.
+func (t *lagTracker) rebalanced(delta int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if delta <= 0 {
+		return
+	}
+	consumerRebalances.WithLabelValues(t.topic).Add(float64(delta))
+	t.rebalances += delta
+	for id := range t.partitions {
+		partition := strconv.Itoa(id)
+		consumerLag.DeleteLabelValues(t.topic, partition)
+		consumerOffset.DeleteLabelValues(t.topic, partition)
+		consumerHighWaterMark.DeleteLabelValues(t.topic, partition)
+		assignmentChanges.WithLabelValues(t.topic, "revoked").Inc()
+	}
+	t.partitions = make(map[int]*PartitionLag)
+	assignedPartitions.WithLabelValues(t.topic).Set(0)
+}
+
+// evaluate marca desde cuándo el lag total supera el límite; requiere mu tomado
+func (t *lagTracker) evaluate(now time.Time) {
+	if t.threshold <= 0 || t.totalLag() <= t.threshold {
+		t.exceededSince = time.Time{}
+		return
+	}
+	if t.exceededSince.IsZero() {
+		t.exceededSince = now
+	}
+}
+
+func (t *lagTracker) totalLag() int64 {
+	var total int64
+	for _, p := range t.partitions {
+		total += p.Lag
+	}
+	return total
+}
+
+// report retorna el estado actual; el consumer está sano si el lag no superó el límite
+// de forma sostenida
+func (t *lagTracker) report(now time.Time) LagReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.evaluate(now)
+	r := LagReport{
+		Topic:      t.topic,
+		Partitions: make([]PartitionLag, 0, len(t.partitions)),
+		TotalLag:   t.totalLag(),
+		Rebalances: t.rebalances,
+		Threshold:  t.threshold,
+		Healthy:    true,
+	}
+	for _, p := range t.partitions {
+		r.Partitions = append(r.Partitions, *p)
+	}
+	sort.Slice(r.Partitions, func(i, j int) bool { return r.Partitions[i].Partition < r.Partitions[j].Partition })
+	if t.threshold > 0 {
+		r.Sustained = t.sustained.String()
+	}
+	if !t.exceededSince.IsZero() {
+		since := t.exceededSince
+		r.ExceededSince = &since
+		r.Healthy = now.Sub(since) < t.sustained
+	}
+	return r
+}
+
+// Lag retorna el lag por partición del consumer y si está dentro del límite configurado
+func (c *Consumer) Lag() LagReport {
+	c.refreshStats()
+	return c.lag.report(time.Now())
+}
+
+// watchLag consulta periódicamente las estadísticas del reader para detectar rebalanceos
+func (c *Consumer) watchLag(ctx context.Context) {
+	ticker := time.NewTicker(lagStatsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.shutdown:
+			return
+		case <-ticker.C:
+			c.refreshStats()
+		}
+	}
+}
+
+func (c *Consumer) refreshStats() {
+	if sr, ok := c.reader.(statsReader); ok {
+		c.lag.rebalanced(sr.Stats().Rebalances)
+	}
+}
@@ -0,0 +1,32 @@
+package kafka
+
+import "github.com/andrew/orquestador-notificacion/internal/metrics"
+
+// Métricas del consumer expuestas en /metrics
+var (
+	eventsConsumed = metrics.NewCounter("orchestrator_events_consumed_total",
+		"Eventos leídos de Kafka por topic y tipo (invalid si el JSON no decodifica, other si no tiene handler)", "topic", "type")
+	commitFailures = metrics.NewCounter("orchestrator_commit_failures_total",
+		"Fallos al confirmar offsets, por topic", "topic")
+	eventRetries = metrics.NewCounter("orchestrator_event_retries_total",
+		"Reintentos de eventos por topic, tipo y motivo (retry_topic o throttled)", "topic", "type", "reason")
+	deadLetters = metrics.NewCounter("orchestrator_dlq_messages_total",
+		"Eventos enviados al topic de dead-letter por tipo y clase de error", "topic", "type", "kind")
+)
+
+const (
+	// Tipo usado en las métricas para los mensajes cuyo JSON no decodifica
+	invalidEventType = "invalid"
+	// Tipo usado en las métricas para los eventos sin handler: el tipo lo elige quien
+	// publica el evento, y usarlo tal cual haría crecer sin límite las series
+	otherEventType = "other"
+)
+
+// metricType retorna la etiqueta type de las métricas del evento: su tipo si tiene handler
+// (tipos fijos o del archivo de routing) u other
+func (c *Consumer) metricType(eventType string) string {
+	if eventType == invalidEventType || c.processor.Handles(eventType) {
+		return eventType
+	}
+	return otherEventType
+}
@@ -0,0 +1,69 @@
+package kafka
+
+import (
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type partitionKey struct {
+	topic     string
+	partition int
+}
+
+// partitionQueue guarda los offsets despachados de una partición en orden de llegada
+type partitionQueue struct {
+	offsets []int64
+	done    map[int64]bool
+}
+
+// offsetTracker calcula, por partición, el mayor offset contiguo ya procesado.
+// Los workers terminan en cualquier orden; solo se confirma hasta el primer hueco.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[partitionKey]*partitionQueue
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{partitions: make(map[partitionKey]*partitionQueue)}
+}
+
+// track registra un mensaje recién obtenido, antes de entregarlo a un worker
+func (t *offsetTracker) track(m kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := partitionKey{topic: m.Topic, partition: m.Partition}
+	q, ok := t.partitions[key]
+	if !ok || (len(q.offsets) > 0 && m.Offset <= q.offsets[len(q.offsets)-1]) {
+		// Partición nueva o reasignada tras un rebalanceo: se reinicia su seguimiento
+		q = &partitionQueue{done: make(map[int64]bool)}
+		t.partitions[key] = q
+	}
+	q.offsets = append(q.offsets, m.Offset)
+}
+
+// ack marca el mensaje como procesado y retorna el mensaje cuyo offset debe confirmarse,
+// o false si todavía hay offsets anteriores pendientes
+func (t *offsetTracker) ack(m kafka.Message) (kafka.Message, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := partitionKey{topic: m.Topic, partition: m.Partition}
+	q, ok := t.partitions[key]
+	if !ok {
+		return kafka.Message{}, false
+	}
+	q.done[m.Offset] = true
+
+	var last int64 = -1
+	for len(q.offsets) > 0 && q.done[q.offsets[0]] {
+		last = q.offsets[0]
+		delete(q.done, last)
+		q.offsets = q.offsets[1:]
+	}
+	if last < 0 {
+		return kafka.Message{}, false
+	}
+	return kafka.Message{Topic: m.Topic, Partition: m.Partition, Offset: last}, true
+}
@@ -0,0 +1,74 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestOffsetTrackerAck(t *testing.T) {
+	msg := func(partition int, offset int64) kafka.Message {
+		return kafka.Message{Topic: "user-events", Partition: partition, Offset: offset}
+	}
+
+	type step struct {
+		ack        kafka.Message
+		wantCommit int64 // -1 = no hay offset para confirmar
+	}
+	tests := []struct {
+		name    string
+		tracked []kafka.Message
+		steps   []step
+	}{
+		{
+			name:    "en orden",
+			tracked: []kafka.Message{msg(0, 10), msg(0, 11), msg(0, 12)},
+			steps:   []step{{msg(0, 10), 10}, {msg(0, 11), 11}, {msg(0, 12), 12}},
+		},
+		{
+			name:    "fuera de orden confirma hasta el primer hueco",
+			tracked: []kafka.Message{msg(0, 10), msg(0, 11), msg(0, 12)},
+			steps:   []step{{msg(0, 12), -1}, {msg(0, 11), -1}, {msg(0, 10), 12}},
+		},
+		{
+			name:    "hueco intermedio",
+			tracked: []kafka.Message{msg(0, 10), msg(0, 11), msg(0, 12)},
+			steps:   []step{{msg(0, 10), 10}, {msg(0, 12), -1}, {msg(0, 11), 12}},
+		},
+		{
+			name:    "particiones independientes",
+			tracked: []kafka.Message{msg(0, 10), msg(1, 50), msg(0, 11)},
+			steps:   []step{{msg(1, 50), 50}, {msg(0, 11), -1}, {msg(0, 10), 11}},
+		},
+		{
+			name:    "partición no registrada",
+			tracked: nil,
+			steps:   []step{{msg(0, 10), -1}},
+		},
+		{
+			name:    "reasignación reinicia el seguimiento",
+			tracked: []kafka.Message{msg(0, 10), msg(0, 11), msg(0, 5)},
+			steps:   []step{{msg(0, 10), -1}, {msg(0, 5), 5}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newOffsetTracker()
+			for _, m := range tt.tracked {
+				tr.track(m)
+			}
+			for i, s := range tt.steps {
+				got, ok := tr.ack(s.ack)
+				switch {
+				case s.wantCommit < 0 && ok:
+					t.Errorf("paso %d: ack(%d) confirmó %d, no se esperaba commit", i, s.ack.Offset, got.Offset)
+				case s.wantCommit >= 0 && (!ok || got.Offset != s.wantCommit):
+					t.Errorf("paso %d: ack(%d) = (%d, %v), se esperaba %d", i, s.ack.Offset, got.Offset, ok, s.wantCommit)
+				case ok && got.Partition != s.ack.Partition:
+					t.Errorf("paso %d: partición %d, se esperaba %d", i, got.Partition, s.ack.Partition)
+				}
+			}
+		})
+	}
+}
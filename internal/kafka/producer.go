@@ -4,56 +4,86 @@ import (
 	"context"
 	"encoding/json"
 
-	"github.com/google/uuid"
+	"github.com/andrew/orquestador-notificacion/internal/domain"
 	"github.com/segmentio/kafka-go"
 )
 
+// MessageWriter abstrae el kafka.Writer para poder sustituirlo por un writer en memoria en pruebas
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
 type Producer struct {
-	writer *kafka.Writer
+	writer MessageWriter
+	status writeStatus
 }
 
 func NewProducer(brokers []string, topic string) *Producer {
-	return &Producer{
-		writer: &kafka.Writer{
-			Addr:     kafka.TCP(brokers...),
-			Topic:    topic,
-			Balancer: &kafka.LeastBytes{},
-		},
-	}
+	return NewProducerWithWriter(&kafka.Writer{
+		Addr:     kafka.TCP(brokers...),
+		Topic:    topic,
+		Balancer: &kafka.LeastBytes{},
+	})
+}
+
+// NewProducerWithWriter crea un producer sobre un writer arbitrario
+func NewProducerWithWriter(w MessageWriter) *Producer {
+	return &Producer{writer: w}
 }
 
 func (p *Producer) Send(ctx context.Context, key []byte, value []byte) error {
-	return p.writer.WriteMessages(ctx, kafka.Message{
+	return p.write(ctx, kafka.Message{
 		Key:   key,
 		Value: value,
 	})
 }
 
+// SendMessage publica un mensaje completo (key, value y headers)
+func (p *Producer) SendMessage(ctx context.Context, m kafka.Message) error {
+	return p.write(ctx, m)
+}
+
+// SendMessages publica varios mensajes en un único lote
+func (p *Producer) SendMessages(ctx context.Context, msgs ...kafka.Message) error {
+	return p.write(ctx, msgs...)
+}
+
+// write propaga la traza y el correlation ID activos, escribe en Kafka y registra el resultado para el check
+// de readiness
+func (p *Producer) write(ctx context.Context, msgs ...kafka.Message) error {
+	traced := make([]kafka.Message, len(msgs))
+	for i, m := range msgs {
+		m.Headers = InjectContext(ctx, m.Headers)
+		traced[i] = m
+	}
+	err := p.writer.WriteMessages(ctx, traced...)
+	p.status.record(err)
+	return err
+}
+
 func (p *Producer) Close() error {
 	return p.writer.Close()
 }
 
 // -------------------- NUEVO --------------------
 
-// NotificationEvent es el contrato de eventos
-type NotificationEvent struct {
-	ID       string                 `json:"id"`
-	Type     string                 `json:"type"`
-	Template string                 `json:"template"`
-	To       string                 `json:"to"`
-	Data     map[string]interface{} `json:"data"`
+// NotificationEvent es el contrato de eventos (definido en domain para que el servicio
+// pueda construirlo sin depender de este paquete)
+type NotificationEvent = domain.NotificationEvent
+
+// NewNotificationEvent construye el evento de notificación con un ID nuevo
+func NewNotificationEvent(eventType, template, to string, data map[string]interface{}) NotificationEvent {
+	return domain.NewNotificationEvent(eventType, template, to, data)
 }
 
 // SendEvent construye el JSON y lo manda
 func (p *Producer) SendEvent(ctx context.Context, eventType, template, to string, data map[string]interface{}) error {
-	event := NotificationEvent{
-		ID:       uuid.New().String(),
-		Type:     eventType,
-		Template: template,
-		To:       to,
-		Data:     data,
-	}
+	return p.PublishEvent(ctx, NewNotificationEvent(eventType, template, to, data))
+}
 
+// PublishEvent publica un NotificationEvent ya construido, usando su ID como key
+func (p *Producer) PublishEvent(ctx context.Context, event NotificationEvent) error {
 	payload, err := json.Marshal(event)
 	if err != nil {
 		return err
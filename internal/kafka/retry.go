@@ -0,0 +1,178 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Headers usados para reprogramar un evento en los topics de reintento
+const (
+	HeaderRetryAttempts      = "x-retry-attempts"
+	HeaderRetryNotBefore     = "x-retry-not-before"
+	HeaderRetryOriginalTopic = "x-retry-original-topic"
+	HeaderRetryFirstFailure  = "x-retry-first-failure"
+	HeaderRetryLastError     = "x-retry-last-error"
+)
+
+// RetryTopicName construye el nombre del topic de reintento, ej: user-events.retry.5m
+func RetryTopicName(baseTopic string, delay time.Duration) string {
+	return baseTopic + ".retry." + formatDelay(delay)
+}
+
+// RetryGroupID construye el consumer group de un topic de reintento, ej:
+// kafka-listener-group-retry-5m. Cada topic tiene su propio grupo para que un reader
+// que entra o sale no rebalancee el topic principal ni los demás escalones.
+func RetryGroupID(groupID string, delay time.Duration) string {
+	return groupID + "-retry-" + formatDelay(delay)
+}
+
+// formatDelay abrevia la duración: 30s, 5m, 1h, 1h30m
+func formatDelay(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// RetryTopics agrupa un producer por cada escalón de la escalera de reintentos
+type RetryTopics struct {
+	baseTopic string
+	producers map[time.Duration]*Producer
+}
+
+// NewRetryTopics crea un producer por cada espera, publicando en <baseTopic>.retry.<espera>
+func NewRetryTopics(brokers []string, baseTopic string, delays []time.Duration) *RetryTopics {
+	rt := &RetryTopics{
+		baseTopic: baseTopic,
+		producers: make(map[time.Duration]*Producer, len(delays)),
+	}
+	for _, d := range delays {
+		rt.producers[d] = NewProducer(brokers, RetryTopicName(baseTopic, d))
+	}
+	return rt
+}
+
+// NewRetryTopicsWithProducers permite inyectar los producers (ej: writers en memoria)
+func NewRetryTopicsWithProducers(baseTopic string, producers map[time.Duration]*Producer) *RetryTopics {
+	return &RetryTopics{baseTopic: baseTopic, producers: producers}
+}
+
+// Delays retorna las esperas de la escalera en orden ascendente
+func (rt *RetryTopics) Delays() []time.Duration {
+	delays := make([]time.Duration, 0, len(rt.producers))
+	for d := range rt.producers {
+		delays = append(delays, d)
+	}
+	sort.Slice(delays, func(i, j int) bool { return delays[i] < delays[j] })
+	return delays
+}
+
+// Topics retorna el nombre de cada topic de reintento y su espera
+func (rt *RetryTopics) Topics() map[time.Duration]string {
+	topics := make(map[time.Duration]string, len(rt.producers))
+	for d := range rt.producers {
+		topics[d] = RetryTopicName(rt.baseTopic, d)
+	}
+	return topics
+}
+
+// Schedule publica el mensaje en el topic de la espera indicada con su marca not-before
+func (rt *RetryTopics) Schedule(ctx context.Context, m kafka.Message, delay time.Duration, failure failureRecord, now time.Time) error {
+	p, ok := rt.producers[delay]
+	if !ok {
+		return fmt.Errorf("no hay topic de reintento para la espera %s", formatDelay(delay))
+	}
+
+	originalTopic := headerValue(m.Headers, HeaderRetryOriginalTopic)
+	if originalTopic == "" {
+		originalTopic = m.Topic
+	}
+
+	headers := withoutHeaders(m.Headers,
+		HeaderRetryAttempts, HeaderRetryNotBefore, HeaderRetryOriginalTopic,
+		HeaderRetryFirstFailure, HeaderRetryLastError)
+	headers = append(headers,
+		kafka.Header{Key: HeaderRetryAttempts, Value: []byte(strconv.Itoa(failure.attempts))},
+		kafka.Header{Key: HeaderRetryNotBefore, Value: []byte(now.Add(delay).UTC().Format(time.RFC3339Nano))},
+		kafka.Header{Key: HeaderRetryOriginalTopic, Value: []byte(originalTopic)},
+		kafka.Header{Key: HeaderRetryFirstFailure, Value: []byte(failure.firstFailure.UTC().Format(time.RFC3339Nano))},
+		kafka.Header{Key: HeaderRetryLastError, Value: []byte(failure.errorMessage())},
+	)
+
+	return p.SendMessage(ctx, kafka.Message{
+		Key:     m.Key,
+		Value:   m.Value,
+		Headers: headers,
+	})
+}
+
+func (rt *RetryTopics) Close() error {
+	var firstErr error
+	for _, p := range rt.producers {
+		if err := p.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// notBefore retorna el instante a partir del cual el mensaje puede procesarse
+func notBefore(m kafka.Message) (time.Time, bool) {
+	raw := headerValue(m.Headers, HeaderRetryNotBefore)
+	if raw == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// failureFromHeaders recupera los intentos previos registrados por los topics de reintento
+func failureFromHeaders(m kafka.Message) failureRecord {
+	var f failureRecord
+	if n, err := strconv.Atoi(headerValue(m.Headers, HeaderRetryAttempts)); err == nil {
+		f.attempts = n
+	}
+	if t, err := time.Parse(time.RFC3339Nano, headerValue(m.Headers, HeaderRetryFirstFailure)); err == nil {
+		f.firstFailure = t
+	}
+	return f
+}
+
+func headerValue(headers []kafka.Header, key string) string {
+	for i := len(headers) - 1; i >= 0; i-- {
+		if headers[i].Key == key {
+			return string(headers[i].Value)
+		}
+	}
+	return ""
+}
+
+func withoutHeaders(headers []kafka.Header, keys ...string) []kafka.Header {
+	out := make([]kafka.Header, 0, len(headers)+len(keys))
+	for _, h := range headers {
+		drop := false
+		for _, k := range keys {
+			if h.Key == k {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			out = append(out, h)
+		}
+	}
+	return out
+}
@@ -0,0 +1,202 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/handler"
+	"github.com/andrew/orquestador-notificacion/internal/logger"
+	"github.com/segmentio/kafka-go"
+)
+
+func TestRetryTopicName(t *testing.T) {
+	tests := []struct {
+		delay time.Duration
+		want  string
+	}{
+		{30 * time.Second, "user-events.retry.30s"},
+		{5 * time.Minute, "user-events.retry.5m"},
+		{time.Hour, "user-events.retry.1h"},
+		{90 * time.Minute, "user-events.retry.1h30m"},
+		{90 * time.Second, "user-events.retry.1m30s"},
+	}
+	for _, tt := range tests {
+		if got := RetryTopicName("user-events", tt.delay); got != tt.want {
+			t.Errorf("RetryTopicName(%s) = %q, se esperaba %q", tt.delay, got, tt.want)
+		}
+	}
+	if got := RetryGroupID("orchestrator", 5*time.Minute); got != "orchestrator-retry-5m" {
+		t.Errorf("RetryGroupID = %q, se esperaba %q", got, "orchestrator-retry-5m")
+	}
+}
+
+func TestNotBefore(t *testing.T) {
+	due := time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC)
+	tests := []struct {
+		name   string
+		header string
+		want   time.Time
+		wantOK bool
+	}{
+		{"sin header", "", time.Time{}, false},
+		{"marca válida", due.Format(time.RFC3339Nano), due, true},
+		{"marca inválida", "mañana", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []kafka.Header
+			if tt.header != "" {
+				headers = append(headers, kafka.Header{Key: HeaderRetryNotBefore, Value: []byte(tt.header)})
+			}
+			got, ok := notBefore(kafka.Message{Headers: headers})
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("notBefore = (%s, %v), se esperaba (%s, %v)", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// TestRetryLadder recorre la escalera: cada fallo reprograma en el escalón siguiente y
+// al agotarla el evento va al DLQ
+func TestRetryLadder(t *testing.T) {
+	delays := []time.Duration{30 * time.Second, 5 * time.Minute, time.Hour}
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		attempts   string // intentos previos registrados en el mensaje
+		wantTopic  string // topic de reintento esperado; vacío = DLQ
+		wantDelay  time.Duration
+		wantHeader string // x-retry-attempts esperado
+	}{
+		{"primer fallo", "", "user-events.retry.30s", 30 * time.Second, "1"},
+		{"segundo fallo", "1", "user-events.retry.5m", 5 * time.Minute, "2"},
+		{"tercer fallo", "2", "user-events.retry.1h", time.Hour, "3"},
+		{"escalera agotada", "3", "", 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := &journal{}
+			writers := make(map[time.Duration]*fakeWriter)
+			producers := make(map[time.Duration]*Producer)
+			for _, d := range delays {
+				writers[d] = &fakeWriter{name: RetryTopicName("user-events", d), journal: j}
+				producers[d] = NewProducerWithWriter(writers[d])
+			}
+			dlq := &fakeWriter{name: "dlq", journal: j}
+			reader := &fakeReader{journal: j}
+			p := newTestProcessor(&failingHandler{eventType: "USER_LOGIN", err: errors.New("timeout del proveedor")},
+				handler.RetryPolicy{Delays: delays})
+			c := newConsumer(reader, "user-events", p, logger.New("[Test]"),
+				WithDeadLetter(NewProducerWithWriter(dlq)),
+				WithRetryTopics(NewRetryTopicsWithProducers("user-events", producers)))
+
+			var headers []kafka.Header
+			topic := "user-events"
+			if tt.attempts != "" {
+				topic = "user-events.retry.x"
+				headers = append(headers,
+					kafka.Header{Key: HeaderRetryAttempts, Value: []byte(tt.attempts)},
+					kafka.Header{Key: HeaderRetryOriginalTopic, Value: []byte("user-events")},
+					kafka.Header{Key: HeaderRetryNotBefore, Value: []byte(now.Add(-time.Second).Format(time.RFC3339Nano))})
+			}
+			m := eventMessage(topic, 0, 9, headers...)
+			c.offsets.track(m)
+			c.processMessage(context.Background(), 0, m)
+
+			wantJournal := []string{"write:dlq", "commit:9"}
+			if tt.wantTopic != "" {
+				wantJournal = []string{"write:" + tt.wantTopic, "commit:9"}
+			}
+			if got := j.list(); !equalStrings(got, wantJournal) {
+				t.Fatalf("secuencia = %v, se esperaba %v", got, wantJournal)
+			}
+			if tt.wantTopic == "" {
+				return
+			}
+
+			got := writers[tt.wantDelay].messages()[0]
+			if v := headerValue(got.Headers, HeaderRetryAttempts); v != tt.wantHeader {
+				t.Errorf("x-retry-attempts = %q, se esperaba %q", v, tt.wantHeader)
+			}
+			if v := headerValue(got.Headers, HeaderRetryOriginalTopic); v != "user-events" {
+				t.Errorf("x-retry-original-topic = %q", v)
+			}
+			due, ok := notBefore(got)
+			if !ok {
+				t.Fatal("el mensaje reprogramado no tiene marca not-before")
+			}
+			if wait := time.Until(due); wait > tt.wantDelay || wait < tt.wantDelay-time.Minute {
+				t.Errorf("not-before a %s, se esperaba ~%s", wait, tt.wantDelay)
+			}
+			if n := countHeader(got.Headers, HeaderRetryAttempts); n != 1 {
+				t.Errorf("x-retry-attempts aparece %d veces", n)
+			}
+		})
+	}
+}
+
+// TestWaitNotBefore verifica que el consumer espere la marca y que la cancelación no confirme
+func TestWaitNotBefore(t *testing.T) {
+	tests := []struct {
+		name    string
+		due     time.Duration
+		cancel  bool
+		wantOK  bool
+		minWait time.Duration
+	}{
+		{"marca vencida", -time.Minute, false, true, 0},
+		{"marca próxima", 50 * time.Millisecond, false, true, 40 * time.Millisecond},
+		{"cancelado durante la espera", time.Hour, true, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConsumer(&fakeReader{journal: &journal{}}, "user-events.retry.1h", nil, logger.New("[Test]"))
+			m := kafka.Message{Headers: []kafka.Header{{
+				Key:   HeaderRetryNotBefore,
+				Value: []byte(time.Now().Add(tt.due).Format(time.RFC3339Nano)),
+			}}}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				time.AfterFunc(20*time.Millisecond, cancel)
+			}
+			start := time.Now()
+			if ok := c.waitNotBefore(ctx, 0, m); ok != tt.wantOK {
+				t.Fatalf("waitNotBefore = %v, se esperaba %v", ok, tt.wantOK)
+			}
+			if waited := time.Since(start); waited < tt.minWait {
+				t.Errorf("esperó %s, se esperaba al menos %s", waited, tt.minWait)
+			}
+		})
+	}
+}
+
+func TestRetryTopicsDelaysOrdered(t *testing.T) {
+	rt := NewRetryTopicsWithProducers("user-events", map[time.Duration]*Producer{
+		time.Hour:        nil,
+		30 * time.Second: nil,
+		5 * time.Minute:  nil,
+	})
+	got := rt.Delays()
+	want := []time.Duration{30 * time.Second, 5 * time.Minute, time.Hour}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Delays = %v, se esperaba %v", got, want)
+		}
+	}
+}
+
+func countHeader(headers []kafka.Header, key string) int {
+	n := 0
+	for _, h := range headers {
+		if h.Key == key {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,52 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/andrew/orquestador-notificacion/internal/kafka")
+
+// headerCarrier adapta los headers de un mensaje al propagador de OpenTelemetry
+type headerCarrier struct {
+	headers *[]kafka.Header
+}
+
+var _ propagation.TextMapCarrier = headerCarrier{}
+
+func (c headerCarrier) Get(key string) string {
+	return headerValue(*c.headers, key)
+}
+
+func (c headerCarrier) Set(key, value string) {
+	*c.headers = append(withoutHeaders(*c.headers, key), kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.headers))
+	for _, h := range *c.headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
+
+// InjectTrace agrega a los headers el contexto de traza activo en ctx (traceparent y
+// tracestate), reemplazando el que trajera el mensaje. Sin traza activa no los modifica.
+func InjectTrace(ctx context.Context, headers []kafka.Header) []kafka.Header {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return headers
+	}
+	propagator := otel.GetTextMapPropagator()
+	headers = withoutHeaders(headers, propagator.Fields()...)
+	propagator.Inject(ctx, headerCarrier{&headers})
+	return headers
+}
+
+// extractTrace registra en ctx el contexto de traza recibido en los headers del mensaje
+func extractTrace(ctx context.Context, m kafka.Message) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, headerCarrier{&m.Headers})
+}
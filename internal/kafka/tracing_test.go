@@ -0,0 +1,86 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceHeadersRoundTrip(t *testing.T) {
+	prev := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(prev) })
+
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	tr := tp.Tracer("test")
+
+	// Sin traza activa los headers no cambian
+	headers := []kafka.Header{{Key: "traceparent", Value: []byte("viejo")}}
+	if got := InjectTrace(context.Background(), headers); len(got) != 1 || string(got[0].Value) != "viejo" {
+		t.Fatalf("InjectTrace sin traza = %v, se esperaban los headers originales", got)
+	}
+
+	ctx, publish := tr.Start(context.Background(), "publish", trace.WithSpanKind(trace.SpanKindProducer))
+	headers = InjectTrace(ctx, []kafka.Header{
+		{Key: "traceparent", Value: []byte("00-aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa-bbbbbbbbbbbbbbbb-01")},
+		{Key: "tracestate", Value: []byte("viejo=1")},
+		{Key: HeaderCorrelationID, Value: []byte("corr-1")},
+	})
+	publish.End()
+
+	var traceparents int
+	for _, h := range headers {
+		switch h.Key {
+		case "traceparent":
+			traceparents++
+		case "tracestate":
+			t.Errorf("quedó el tracestate del mensaje original: %q", h.Value)
+		}
+	}
+	if traceparents != 1 {
+		t.Fatalf("headers con %d traceparent, se esperaba 1: %v", traceparents, headers)
+	}
+	if got := headerValue(headers, HeaderCorrelationID); got != "corr-1" {
+		t.Errorf("correlation ID = %q, se esperaba conservarlo", got)
+	}
+
+	ctx = extractTrace(context.Background(), kafka.Message{Headers: headers})
+	_, process := tr.Start(ctx, "process", trace.WithSpanKind(trace.SpanKindConsumer))
+	process.End()
+
+	spans := exp.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("se exportaron %d spans, se esperaban 2", len(spans))
+	}
+	parent, child := spans[0], spans[1]
+	if child.SpanContext.TraceID() != parent.SpanContext.TraceID() {
+		t.Errorf("trace ID del consumer = %s, se esperaba %s", child.SpanContext.TraceID(), parent.SpanContext.TraceID())
+	}
+	if child.Parent.SpanID() != parent.SpanContext.SpanID() || !child.Parent.IsRemote() {
+		t.Errorf("padre del consumer = %s (remoto %v), se esperaba el span remoto %s",
+			child.Parent.SpanID(), child.Parent.IsRemote(), parent.SpanContext.SpanID())
+	}
+}
+
+func TestExtractTraceWithoutHeaders(t *testing.T) {
+	prev := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(prev) })
+
+	for _, value := range []string{"", "00-invalido"} {
+		m := kafka.Message{}
+		if value != "" {
+			m.Headers = []kafka.Header{{Key: "traceparent", Value: []byte(value)}}
+		}
+		if sc := trace.SpanContextFromContext(extractTrace(context.Background(), m)); sc.IsValid() {
+			t.Errorf("traceparent %q produjo un contexto válido: %v", value, sc)
+		}
+	}
+}
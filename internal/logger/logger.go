@@ -2,6 +2,7 @@
 package logger
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -11,6 +12,7 @@ import (
 // Logger estructura para logging en formato JSON
 type Logger struct {
 	loggerName string
+	fields     map[string]interface{} // campos fijos (ej: correlation_id) agregados por WithContext
 }
 
 // LogPayload estructura del log JSON (igual a Logger.js)
@@ -47,7 +49,10 @@ func (l *Logger) log(level, message string, meta map[string]interface{}) {
 		"thread":    payload.Thread,
 	}
 
-	// Agregar campos meta
+	// Agregar campos del contexto y luego los meta, que tienen prioridad
+	for k, v := range l.fields {
+		result[k] = v
+	}
 	for k, v := range meta {
 		result[k] = v
 	}
@@ -104,3 +109,57 @@ func (l *Logger) Fatal(message string, meta map[string]interface{}) {
 	l.log("error", message, meta)
 	os.Exit(1)
 }
+
+type correlationKey struct{}
+type eventIDKey struct{}
+
+// WithCorrelationID guarda en el contexto el identificador que une los logs de un mismo
+// flujo entre servicios (header x-correlation-id)
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationID retorna el identificador de correlación del contexto, o vacío
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// WithEventID guarda en el contexto el ID del evento en proceso
+func WithEventID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, eventIDKey{}, id)
+}
+
+// EventID retorna el ID del evento en proceso guardado en el contexto, o vacío
+func EventID(ctx context.Context) string {
+	id, _ := ctx.Value(eventIDKey{}).(string)
+	return id
+}
+
+// WithContext retorna un logger que agrega a cada línea el correlation_id y el event_id
+// del contexto
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	correlationID := CorrelationID(ctx)
+	eventID := EventID(ctx)
+	if correlationID == "" && eventID == "" {
+		return l
+	}
+
+	fields := make(map[string]interface{}, len(l.fields)+2)
+	for k, v := range l.fields {
+		fields[k] = v
+	}
+	if correlationID != "" {
+		fields["correlation_id"] = correlationID
+	}
+	if eventID != "" {
+		fields["event_id"] = eventID
+	}
+	return &Logger{loggerName: l.loggerName, fields: fields}
+}
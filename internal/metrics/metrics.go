@@ -0,0 +1,54 @@
+// Package metrics agrupa las métricas del orquestador en un registry de client_golang y
+// las expone en el formato de Prometheus.
+//
+// Cada paquete declara sus métricas como variables sobre Registry:
+//
+//	var published = metrics.NewCounter("orchestrator_notifications_published_total",
+//		"Notificaciones publicadas", "channel", "template")
+//
+//	published.WithLabelValues("EMAIL", "welcome").Inc()
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry es el registry que expone el endpoint /metrics. Además de las métricas del
+// orquestador incluye las del runtime de Go y del proceso.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// NewCounter registra un contador con las etiquetas indicadas
+func NewCounter(name, help string, labels ...string) *prometheus.CounterVec {
+	return factory.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels)
+}
+
+// NewGauge registra un gauge con las etiquetas indicadas
+func NewGauge(name, help string, labels ...string) *prometheus.GaugeVec {
+	return factory.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: help}, labels)
+}
+
+// NewHistogram registra un histograma con las etiquetas indicadas; buckets nil usa
+// prometheus.DefBuckets
+func NewHistogram(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+	return factory.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}, labels)
+}
+
+// Handler expone Registry en el formato de Prometheus (texto u OpenMetrics según el
+// encabezado Accept del scraper)
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
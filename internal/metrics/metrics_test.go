@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestHandlerExposition(t *testing.T) {
+	counter := NewCounter("test_sent_total", "Enviados por template", "template")
+	counter.WithLabelValues("welcome \"v2\"\nes\\co").Add(2)
+	histogram := NewHistogram("test_duration_seconds", "Duración", []float64{0.1, 1}, "handler")
+	for _, v := range []float64{0.05, 0.5, 0.7, 3} {
+		histogram.WithLabelValues("login").Observe(v)
+	}
+
+	out := scrape(t)
+	for _, want := range []string{
+		"# TYPE test_sent_total counter",
+		`test_sent_total{template="welcome \"v2\"\nes\\co"} 2`,
+		"# TYPE test_duration_seconds histogram",
+		`test_duration_seconds_bucket{handler="login",le="0.1"} 1`,
+		`test_duration_seconds_bucket{handler="login",le="1"} 3`,
+		`test_duration_seconds_bucket{handler="login",le="+Inf"} 4`,
+		`test_duration_seconds_sum{handler="login"} 4.25`,
+		`test_duration_seconds_count{handler="login"} 4`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("falta %q en la exposición:\n%s", want, out)
+		}
+	}
+}
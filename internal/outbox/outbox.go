@@ -0,0 +1,420 @@
+// Package outbox implementa un outbox transaccional local: las notificaciones se escriben
+// primero en un log append-only en disco y un relay las publica en Kafka con reintentos.
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/appendlog"
+	"github.com/andrew/orquestador-notificacion/internal/errs"
+	kafkaPkg "github.com/andrew/orquestador-notificacion/internal/kafka"
+	"github.com/andrew/orquestador-notificacion/internal/logger"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	opPut      = "put"
+	opSent     = "sent"
+	opRejected = "rejected"
+
+	// batchSize es la cantidad máxima de entradas publicadas por el relay en un solo lote
+	batchSize = 100
+)
+
+// Sender publica mensajes en el topic de destino (implementado por kafka.Producer)
+type Sender interface {
+	SendMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// Entry es una notificación pendiente de publicar
+type Entry struct {
+	Seq       uint64         `json:"seq"`
+	Key       []byte         `json:"key,omitempty"`
+	Value     []byte         `json:"value"`
+	Headers   []kafka.Header `json:"headers,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// record es una línea del log: alta de una entrada o marca de enviada (o rechazada)
+type record struct {
+	Op    string `json:"op"`
+	Entry *Entry `json:"entry,omitempty"`
+	Seq   uint64 `json:"seq,omitempty"`
+}
+
+// rejection es una línea del archivo de rechazadas (<path>.rejected): una entrada que el
+// broker no aceptará nunca (ej: mensaje demasiado grande), apartada para revisión manual
+type rejection struct {
+	Entry      *Entry    `json:"entry"`
+	Error      string    `json:"error"`
+	RejectedAt time.Time `json:"rejected_at"`
+}
+
+// errNotAttempted marca las entradas de un lote que no se publicaron porque otra entrada
+// del mismo lote fue rechazada; se reintentan de inmediato
+var errNotAttempted = errors.New("no publicada: otra entrada del lote fue rechazada")
+
+// Outbox implementa service.Producer escribiendo en el log local en lugar de en Kafka
+type Outbox struct {
+	mu       sync.Mutex
+	log      *appendlog.Log[record]
+	rejects  *appendlog.Log[rejection]
+	pending  []*Entry
+	nextSeq  uint64
+	rejected int // entradas apartadas en el archivo de rechazadas
+
+	sender   Sender
+	logger   *logger.Logger
+	notify   chan struct{}
+	retryMin time.Duration
+	retryMax time.Duration
+	relayErr error // resultado del último intento del relay
+}
+
+// Open abre (o crea) el log del outbox y recupera las entradas que no llegaron a enviarse
+func Open(path string, sender Sender, log *logger.Logger) (*Outbox, error) {
+	o := &Outbox{
+		sender:   sender,
+		logger:   log,
+		notify:   make(chan struct{}, 1),
+		retryMin: 500 * time.Millisecond,
+		retryMax: 30 * time.Second,
+		nextSeq:  1,
+	}
+
+	entries := make(map[uint64]*Entry)
+	l, err := appendlog.Open(path, "outbox", func(rec record) {
+		switch rec.Op {
+		case opPut:
+			if rec.Entry == nil {
+				return
+			}
+			entries[rec.Entry.Seq] = rec.Entry
+			if rec.Entry.Seq >= o.nextSeq {
+				o.nextSeq = rec.Entry.Seq + 1
+			}
+		case opSent, opRejected:
+			delete(entries, rec.Seq)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	o.log = l
+	o.rejects, err = appendlog.Open(path+".rejected", "rechazadas del outbox", func(rejection) { o.rejected++ })
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	for _, e := range entries {
+		o.pending = append(o.pending, e)
+	}
+	sort.Slice(o.pending, func(i, j int) bool { return o.pending[i].Seq < o.pending[j].Seq })
+
+	if err := o.rewrite(); err != nil {
+		o.Close()
+		return nil, err
+	}
+	return o, nil
+}
+
+// Send guarda el mensaje en el outbox; retorna solo cuando quedó persistido en disco
+func (o *Outbox) Send(ctx context.Context, key []byte, value []byte) error {
+	return o.Enqueue(ctx, kafka.Message{Key: key, Value: value})
+}
+
+// SendEvent construye el NotificationEvent y lo guarda en el outbox
+func (o *Outbox) SendEvent(ctx context.Context, eventType, template, to string, data map[string]interface{}) error {
+	return o.PublishEvent(ctx, kafkaPkg.NewNotificationEvent(eventType, template, to, data))
+}
+
+// PublishEvent guarda en el outbox un NotificationEvent ya construido
+func (o *Outbox) PublishEvent(ctx context.Context, event kafkaPkg.NotificationEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return o.Send(ctx, []byte(event.ID), payload)
+}
+
+// Enqueue persiste un mensaje completo en el outbox y despierta al relay. La traza y el
+// correlation ID activos se guardan en los headers para que el relay la publique con el mensaje.
+func (o *Outbox) Enqueue(ctx context.Context, m kafka.Message) error {
+	m.Headers = kafkaPkg.InjectContext(ctx, m.Headers)
+	o.mu.Lock()
+	e := &Entry{
+		Seq:       o.nextSeq,
+		Key:       m.Key,
+		Value:     m.Value,
+		Headers:   m.Headers,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := o.log.Append(record{Op: opPut, Entry: e}); err != nil {
+		o.mu.Unlock()
+		return err
+	}
+	o.nextSeq++
+	o.pending = append(o.pending, e)
+	o.mu.Unlock()
+
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Pending retorna la cantidad de entradas aún no publicadas
+func (o *Outbox) Pending() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.pending)
+}
+
+// Check es un health.Check de readiness: falla si el log está cerrado o el último intento
+// del relay no pudo publicar
+func (o *Outbox) Check(_ context.Context) (map[string]interface{}, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	data := map[string]interface{}{"pending": len(o.pending), "rejected": o.rejected}
+	if len(o.pending) > 0 {
+		data["oldest"] = o.pending[0].CreatedAt.Format(time.RFC3339)
+	}
+	if o.log.Closed() {
+		return data, errors.New("outbox cerrado")
+	}
+	if o.relayErr != nil {
+		return data, fmt.Errorf("relay sin poder publicar: %w", o.relayErr)
+	}
+	return data, nil
+}
+
+// Run es el relay: publica las entradas pendientes en orden, reintentando con backoff
+// exponencial hasta que el contexto termine. Las entradas que el broker rechaza de forma
+// permanente se apartan en el archivo de rechazadas para no bloquear a las siguientes.
+func (o *Outbox) Run(ctx context.Context) {
+	backoff := o.retryMin
+	for {
+		batch := o.nextBatch()
+		if len(batch) == 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-o.notify:
+			}
+			continue
+		}
+
+		msgs := make([]kafka.Message, len(batch))
+		for i, e := range batch {
+			msgs[i] = kafka.Message{Key: e.Key, Value: e.Value, Headers: e.Headers}
+		}
+
+		err := o.sender.SendMessages(ctx, msgs...)
+		results := classify(batch, err)
+		var failed, rejected int
+		var retryErr error
+		for i, result := range results {
+			switch {
+			case result == nil:
+			case isRejected(result):
+				rejected++
+				o.logger.Error("Entrada del outbox rechazada por el broker, se aparta", map[string]interface{}{
+					"error": result.Error(),
+					"seq":   batch[i].Seq,
+				})
+			default:
+				failed++
+				if !errors.Is(result, errNotAttempted) {
+					retryErr = result
+				}
+			}
+		}
+
+		settleErr := o.settle(batch, results)
+		if settleErr != nil {
+			// Las entradas se volverán a publicar tras reiniciar; el consumidor final debe
+			// deduplicar por el ID del NotificationEvent
+			o.logger.Error("Fallo al marcar entradas del outbox como enviadas", map[string]interface{}{
+				"error":   settleErr.Error(),
+				"entries": len(batch),
+			})
+		}
+		o.mu.Lock()
+		o.relayErr = retryErr
+		o.mu.Unlock()
+
+		// Si se apartaron entradas, el resto del lote se reintenta sin esperar
+		if settleErr == nil && (failed == 0 || rejected > 0) {
+			backoff = o.retryMin
+			continue
+		}
+		if retryErr != nil {
+			o.logger.Warn("Fallo al publicar entradas del outbox, se reintentará", map[string]interface{}{
+				"error":   retryErr.Error(),
+				"entries": failed,
+				"backoff": backoff.String(),
+			})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > o.retryMax {
+			backoff = o.retryMax
+		}
+	}
+}
+
+// classify reparte el resultado de publicar un lote entre sus entradas: nil si la entrada
+// se publicó, o el error que la afectó
+func classify(batch []*Entry, err error) []error {
+	results := make([]error, len(batch))
+	if err == nil {
+		return results
+	}
+
+	// El writer rechaza el lote completo antes de enviarlo e indica el mensaje culpable
+	var tooLarge kafka.MessageTooLargeError
+	if errors.As(err, &tooLarge) {
+		found := false
+		for i, e := range batch {
+			if !found && bytes.Equal(e.Key, tooLarge.Message.Key) && bytes.Equal(e.Value, tooLarge.Message.Value) {
+				results[i] = err
+				found = true
+				continue
+			}
+			results[i] = errNotAttempted
+		}
+		if found {
+			return results
+		}
+	}
+
+	// Error por mensaje cuando el lote se repartió entre varias particiones
+	var writeErrs kafka.WriteErrors
+	if errors.As(err, &writeErrs) && len(writeErrs) == len(batch) {
+		copy(results, writeErrs)
+		return results
+	}
+
+	for i := range results {
+		results[i] = err
+	}
+	return results
+}
+
+// isRejected indica si el broker no aceptará nunca el mensaje, por lo que reintentarlo
+// solo bloquearía al resto del outbox. Los errores de conexión, autorización o
+// disponibilidad de particiones se reintentan siempre.
+func isRejected(err error) bool {
+	if errs.IsPermanent(err) {
+		return true
+	}
+	for _, code := range []kafka.Error{
+		kafka.MessageSizeTooLarge,
+		kafka.RecordListTooLarge,
+		kafka.InvalidRecord,
+		kafka.InvalidTimestamp,
+	} {
+		if errors.Is(err, code) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Outbox) nextBatch() []*Entry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	n := len(o.pending)
+	if n > batchSize {
+		n = batchSize
+	}
+	batch := make([]*Entry, n)
+	copy(batch, o.pending[:n])
+	return batch
+}
+
+// settle quita de las pendientes las entradas publicadas y las rechazadas, apartando estas
+// últimas en el archivo de rechazadas. Las que fallaron siguen al frente, en orden.
+func (o *Outbox) settle(batch []*Entry, results []error) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var recs []record
+	var rejections []rejection
+	var keep []*Entry
+	now := time.Now().UTC()
+	for i, e := range batch {
+		switch err := results[i]; {
+		case err == nil:
+			recs = append(recs, record{Op: opSent, Seq: e.Seq})
+		case isRejected(err):
+			rejections = append(rejections, rejection{Entry: e, Error: err.Error(), RejectedAt: now})
+		default:
+			keep = append(keep, e)
+		}
+	}
+
+	var rejectErr error
+	if len(rejections) > 0 {
+		if rejectErr = o.rejects.Append(rejections...); rejectErr != nil {
+			// Sin registro de la rechazada no se descarta: se volverá a intentar
+			for _, r := range rejections {
+				keep = append(keep, r.Entry)
+			}
+			sort.Slice(keep, func(i, j int) bool { return keep[i].Seq < keep[j].Seq })
+		} else {
+			o.rejected += len(rejections)
+			for _, r := range rejections {
+				recs = append(recs, record{Op: opRejected, Seq: r.Entry.Seq})
+			}
+		}
+	}
+
+	// Las entradas del lote son siempre las primeras pendientes
+	o.pending = append(keep, o.pending[len(batch):]...)
+	if len(recs) > 0 {
+		if err := o.log.Append(recs...); err != nil {
+			return err
+		}
+	}
+	if rejectErr != nil {
+		return rejectErr
+	}
+
+	if o.log.ShouldCompact(len(o.pending)) {
+		return o.rewrite()
+	}
+	return nil
+}
+
+// rewrite compacta el log dejando solo las entradas pendientes. Requiere el lock tomado
+// (o ejecutarse durante la construcción).
+func (o *Outbox) rewrite() error {
+	recs := make([]record, len(o.pending))
+	for i, e := range o.pending {
+		recs[i] = record{Op: opPut, Entry: e}
+	}
+	return o.log.Rewrite(recs)
+}
+
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.rejects != nil {
+		o.rejects.Close()
+	}
+	return o.log.Close()
+}
@@ -0,0 +1,183 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/logger"
+	"github.com/segmentio/kafka-go"
+)
+
+// fakeSender responde a cada SendMessages con el siguiente resultado del guion; agotado
+// el guion, publica todo
+type fakeSender struct {
+	mu        sync.Mutex
+	script    []func(msgs []kafka.Message) error
+	published []string
+}
+
+func (s *fakeSender) SendMessages(_ context.Context, msgs ...kafka.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.script) > 0 {
+		step := s.script[0]
+		s.script = s.script[1:]
+		if err := step(msgs); err != nil {
+			return err
+		}
+	}
+	for _, m := range msgs {
+		s.published = append(s.published, string(m.Key))
+	}
+	return nil
+}
+
+func (s *fakeSender) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.published...)
+}
+
+func fail(err error) func([]kafka.Message) error {
+	return func([]kafka.Message) error { return err }
+}
+
+func TestRunClassifiesErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		script       []func([]kafka.Message) error
+		wantKeys     []string
+		wantRejected []string
+	}{
+		{
+			name:     "error de red: se reintenta el lote completo",
+			script:   []func([]kafka.Message) error{fail(errors.New("dial tcp: connection refused"))},
+			wantKeys: []string{"a", "b", "c"},
+		},
+		{
+			name: "mensaje demasiado grande: se aparta y el resto se publica",
+			script: []func([]kafka.Message) error{func(msgs []kafka.Message) error {
+				return kafka.MessageTooLargeError{Message: msgs[1], Remaining: []kafka.Message{msgs[0], msgs[2]}}
+			}},
+			wantKeys:     []string{"a", "c"},
+			wantRejected: []string{"b"},
+		},
+		{
+			name: "errores por mensaje: se aparta el rechazado y se reintenta el temporal",
+			script: []func([]kafka.Message) error{
+				fail(kafka.WriteErrors{nil, kafka.InvalidRecord, kafka.LeaderNotAvailable}),
+			},
+			// a se publicó en el primer intento; c se reintenta
+			wantKeys:     []string{"c"},
+			wantRejected: []string{"b"},
+		},
+		{
+			name:     "error no temporal de autorización: no se descarta",
+			script:   []func([]kafka.Message) error{fail(kafka.TopicAuthorizationFailed), fail(kafka.TopicAuthorizationFailed)},
+			wantKeys: []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "outbox.log")
+			sender := &fakeSender{script: tt.script}
+			o, err := Open(path, sender, logger.New("[Test]"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			o.retryMin = time.Millisecond
+			o.retryMax = time.Millisecond
+			for _, key := range []string{"a", "b", "c"} {
+				if err := o.Enqueue(context.Background(), kafka.Message{Key: []byte(key), Value: []byte("v-" + key)}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				o.Run(ctx)
+				close(done)
+			}()
+			deadline := time.Now().Add(2 * time.Second)
+			for o.Pending() > 0 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			cancel()
+			<-done
+
+			if n := o.Pending(); n != 0 {
+				t.Fatalf("quedaron %d entradas pendientes", n)
+			}
+			if got := sender.keys(); !equalStrings(got, tt.wantKeys) {
+				t.Errorf("publicadas = %v, se esperaba %v", got, tt.wantKeys)
+			}
+			data, err := o.Check(context.Background())
+			if err != nil {
+				t.Errorf("Check: %v", err)
+			}
+			if data["rejected"] != len(tt.wantRejected) {
+				t.Errorf("rejected = %v, se esperaba %d", data["rejected"], len(tt.wantRejected))
+			}
+			if err := o.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := rejectedKeys(t, path+".rejected"); !equalStrings(got, tt.wantRejected) {
+				t.Errorf("apartadas = %v, se esperaba %v", got, tt.wantRejected)
+			}
+
+			// Ni las publicadas ni las apartadas vuelven a quedar pendientes tras reabrir
+			reopened, err := Open(path, &fakeSender{}, logger.New("[Test]"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reopened.Close()
+			if n := reopened.Pending(); n != 0 {
+				t.Errorf("tras reabrir quedaron %d entradas pendientes", n)
+			}
+		})
+	}
+}
+
+func rejectedKeys(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var keys []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r rejection
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatal(err)
+		}
+		if r.Error == "" {
+			t.Errorf("entrada %s apartada sin error", r.Entry.Key)
+		}
+		keys = append(keys, string(r.Entry.Key))
+	}
+	return keys
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
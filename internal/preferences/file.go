@@ -0,0 +1,97 @@
+package preferences
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStore guarda las preferencias en un archivo JSON local. Cada Put reescribe el archivo
+// de forma atómica (archivo temporal + rename).
+type FileStore struct {
+	mu    sync.RWMutex
+	path  string
+	prefs map[string]Preferences
+}
+
+// NewFileStore abre (o crea) el archivo de preferencias
+func NewFileStore(path string) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("crear directorio de preferencias: %w", err)
+	}
+
+	s := &FileStore{path: path, prefs: make(map[string]Preferences)}
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("leer preferencias: %w", err)
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &s.prefs); err != nil {
+			return nil, fmt.Errorf("parsear preferencias %s: %w", path, err)
+		}
+	}
+	return s, nil
+}
+
+func (s *FileStore) Get(_ context.Context, userID string) (*Preferences, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.prefs[userID]
+	if !ok {
+		return nil, false, nil
+	}
+	return &p, true, nil
+}
+
+func (s *FileStore) Put(_ context.Context, p Preferences) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, existed := s.prefs[p.UserID]
+	s.prefs[p.UserID] = p
+	if err := s.flush(); err != nil {
+		if existed {
+			s.prefs[p.UserID] = prev
+		} else {
+			delete(s.prefs, p.UserID)
+		}
+		return err
+	}
+	return nil
+}
+
+// flush reescribe el archivo completo. Requiere el lock tomado.
+func (s *FileStore) flush() error {
+	raw, err := json.MarshalIndent(s.prefs, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := s.path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("escribir preferencias: %w", err)
+	}
+	if _, err := f.Write(raw); err != nil {
+		f.Close()
+		return fmt.Errorf("escribir preferencias: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("sincronizar preferencias: %w", err)
+	}
+	f.Close()
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("escribir preferencias: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) Close() error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package preferences
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore guarda las preferencias en memoria (se pierden al reiniciar)
+type MemoryStore struct {
+	mu    sync.RWMutex
+	prefs map[string]Preferences
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{prefs: make(map[string]Preferences)}
+}
+
+func (s *MemoryStore) Get(_ context.Context, userID string) (*Preferences, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.prefs[userID]
+	if !ok {
+		return nil, false, nil
+	}
+	return &p, true, nil
+}
+
+func (s *MemoryStore) Put(_ context.Context, p Preferences) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prefs[p.UserID] = p
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
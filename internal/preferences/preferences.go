@@ -0,0 +1,140 @@
+// Package preferences guarda las preferencias de notificación de cada usuario:
+// canales permitidos, templates a los que renunció e idioma preferido.
+package preferences
+
+import (
+	"context"
+	"time"
+)
+
+// Preferences son las preferencias de un usuario
+type Preferences struct {
+	UserID string `json:"user_id"`
+	// Channels son los canales permitidos; vacío significa todos
+	Channels []string `json:"channels,omitempty"`
+	// OptOutTemplates son los templates que el usuario no desea recibir
+	OptOutTemplates []string `json:"opt_out_templates,omitempty"`
+	Language        string   `json:"language,omitempty"`
+	// TimeZone es una zona IANA (ej: America/Bogota); vacío equivale a UTC
+	TimeZone   string      `json:"time_zone,omitempty"`
+	QuietHours *QuietHours `json:"quiet_hours,omitempty"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// QuietHours es la ventana diaria (hora local "HH:MM") en la que no se envían
+// notificaciones no urgentes. Si Start > End la ventana cruza la medianoche.
+type QuietHours struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// QuietUntil indica si now cae en las horas de silencio del usuario y, en ese caso,
+// el instante en que la ventana termina. Zonas u horas inválidas desactivan la ventana.
+func (p *Preferences) QuietUntil(now time.Time) (time.Time, bool) {
+	if p.QuietHours == nil {
+		return time.Time{}, false
+	}
+	loc := time.UTC
+	if p.TimeZone != "" {
+		l, err := time.LoadLocation(p.TimeZone)
+		if err != nil {
+			return time.Time{}, false
+		}
+		loc = l
+	}
+	start, okStart := parseClock(p.QuietHours.Start)
+	end, okEnd := parseClock(p.QuietHours.End)
+	if !okStart || !okEnd || start == end {
+		return time.Time{}, false
+	}
+
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	endOn := func(dayOffset int) time.Time {
+		return time.Date(local.Year(), local.Month(), local.Day()+dayOffset, end/60, end%60, 0, 0, loc)
+	}
+
+	if start < end {
+		if minute >= start && minute < end {
+			return endOn(0), true
+		}
+		return time.Time{}, false
+	}
+	// Ventana nocturna, ej: 22:00 - 07:00
+	if minute < end {
+		return endOn(0), true
+	}
+	if minute >= start {
+		return endOn(1), true
+	}
+	return time.Time{}, false
+}
+
+// parseClock convierte "HH:MM" en minutos desde la medianoche
+func parseClock(s string) (int, bool) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// AllowsChannel indica si el usuario acepta notificaciones por el canal
+func (p *Preferences) AllowsChannel(channel string) bool {
+	if len(p.Channels) == 0 {
+		return true
+	}
+	return contains(p.Channels, channel)
+}
+
+// OptedOut indica si el usuario renunció al template
+func (p *Preferences) OptedOut(template string) bool {
+	return contains(p.OptOutTemplates, template)
+}
+
+// Store persiste las preferencias por id de usuario
+type Store interface {
+	// Get retorna las preferencias del usuario; ok es false si no tiene
+	Get(ctx context.Context, userID string) (prefs *Preferences, ok bool, err error)
+	Put(ctx context.Context, prefs Preferences) error
+	Close() error
+}
+
+// Policy decide si una notificación respeta las preferencias del usuario
+type Policy struct {
+	// Mandatory son los templates de seguridad que no admiten opt-out (ej: password_recovery)
+	Mandatory map[string]bool
+}
+
+// NewPolicy crea la política con los templates obligatorios indicados
+func NewPolicy(mandatory []string) Policy {
+	m := make(map[string]bool, len(mandatory))
+	for _, t := range mandatory {
+		m[t] = true
+	}
+	return Policy{Mandatory: m}
+}
+
+// Allow retorna si se debe enviar y, si no, el motivo. Los templates obligatorios
+// se envían siempre, sin importar canales ni opt-outs.
+func (p Policy) Allow(prefs *Preferences, channel, template string) (bool, string) {
+	if prefs == nil || p.Mandatory[template] {
+		return true, ""
+	}
+	if prefs.OptedOut(template) {
+		return false, "template_opt_out"
+	}
+	if !prefs.AllowsChannel(channel) {
+		return false, "channel_not_allowed"
+	}
+	return true, ""
+}
+
+func contains(list []string, v string) bool {
+	for _, it := range list {
+		if it == v {
+			return true
+		}
+	}
+	return false
+}
@@ -2,39 +2,209 @@ package processor
 
 import (
 	"context"
+	"fmt"
+	"time"
+
 	"github.com/andrew/orquestador-notificacion/internal/domain"
+	"github.com/andrew/orquestador-notificacion/internal/errs"
 	"github.com/andrew/orquestador-notificacion/internal/handler"
+	"github.com/andrew/orquestador-notificacion/internal/i18n"
+	"github.com/andrew/orquestador-notificacion/internal/idempotency"
 	"github.com/andrew/orquestador-notificacion/internal/logger"
+	"github.com/andrew/orquestador-notificacion/internal/metrics"
+	"github.com/andrew/orquestador-notificacion/internal/progress"
+	"github.com/andrew/orquestador-notificacion/internal/timer"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Métricas de los handlers expuestas en /metrics
+var (
+	handlerDuration = metrics.NewHistogram("orchestrator_handler_duration_seconds",
+		"Duración de cada handler por tipo de evento", nil, "event_type", "handler")
+	handlerErrors = metrics.NewCounter("orchestrator_handler_errors_total",
+		"Errores de handlers por tipo de evento y clase de error", "event_type", "handler", "kind")
 )
 
+var tracer = otel.Tracer("github.com/andrew/orquestador-notificacion/internal/processor")
+
+// HandlerError envuelve el error de un handler junto con su nombre
+type HandlerError struct {
+	Handler string
+	Err     error
+}
+
+func (e *HandlerError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Handler, e.Err)
+}
+
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
 type Processor struct {
 	registry *handler.Registry
 	logger   *logger.Logger
+	dedupe   idempotency.Store
+	progress idempotency.Store
+	timers   timer.Store
+}
+
+// Option configura aspectos opcionales del Processor
+type Option func(*Processor)
+
+// WithDedupeStore descarta los eventos cuyo ID ya fue procesado por completo
+func WithDedupeStore(s idempotency.Store) Option {
+	return func(p *Processor) {
+		p.dedupe = s
+	}
 }
 
-func NewProcessor(reg *handler.Registry, log *logger.Logger) *Processor {
-	return &Processor{registry: reg, logger: log}
+// WithProgressStore habilita el registro de pasos por evento (ver paquete progress)
+func WithProgressStore(s idempotency.Store) Option {
+	return func(p *Processor) {
+		p.progress = s
+	}
+}
+
+func NewProcessor(reg *handler.Registry, log *logger.Logger, opts ...Option) *Processor {
+	p := &Processor{registry: reg, logger: log}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 func (p *Processor) Process(ctx context.Context, e *domain.Event) error {
+	ctx = withCorrelation(ctx, e)
+	if p.alreadyProcessed(ctx, e) {
+		p.logger.WithContext(ctx).Info("Evento ya procesado, se omite", map[string]interface{}{
+			"event_type": e.Type,
+			"event_id":   e.ID,
+		})
+		return nil
+	}
+
+	// Un evento programado a futuro se guarda como timer y se procesa al vencer (FireTimer)
+	deferred, err := p.schedule(ctx, e)
+	if err != nil {
+		p.logger.WithContext(ctx).Error("Fallo al programar evento", map[string]interface{}{
+			"error":      err.Error(),
+			"event_type": e.Type,
+			"event_id":   e.ID,
+		})
+		return err
+	}
+	if deferred {
+		p.markProcessed(ctx, e)
+		return nil
+	}
+
+	return p.dispatch(ctx, e)
+}
+
+// withCorrelation agrega al contexto el ID del evento y, si el consumer no lo hizo, su
+// correlation ID, para que los logs de handlers y servicios los incluyan
+func withCorrelation(ctx context.Context, e *domain.Event) context.Context {
+	if logger.CorrelationID(ctx) == "" {
+		id := e.CorrelationID
+		if id == "" {
+			id = e.ID
+		}
+		ctx = logger.WithCorrelationID(ctx, id)
+	}
+	return logger.WithEventID(ctx, e.ID)
+}
+
+// dispatch ejecuta los handlers del evento y lo registra como procesado
+func (p *Processor) dispatch(ctx context.Context, e *domain.Event) error {
+	p.cancelPending(ctx, e)
+
 	hs, err := p.registry.GetHandlers(e.Type)
 	if err != nil {
-		p.logger.Warn("No se encontraron handlers para el tipo de evento", map[string]interface{}{
+		p.logger.WithContext(ctx).Warn("No se encontraron handlers para el tipo de evento", map[string]interface{}{
 			"type": e.Type,
 		})
 		return nil // opcional: no es error si no hay handler; depende de tu política
 	}
 
+	// Los handlers pueden registrar pasos completados a través del contexto
+	ctx = progress.WithEvent(ctx, p.progress, e.ID)
+	ctx = i18n.WithLocale(ctx, e.Locale)
+
 	// Llamar handlers en secuencia (podrías paralelizar si son independientes)
 	for _, h := range hs {
-		if err := h.Handle(ctx, e); err != nil {
-			p.logger.Error("Error en handler", map[string]interface{}{
-				"error":     err.Error(),
+		name := handlerName(h)
+		hctx, span := tracer.Start(ctx, "handle "+e.Type, trace.WithAttributes(
+			attribute.String("handler", name),
+			attribute.String("event.id", e.ID),
+		))
+		start := time.Now()
+		err := h.Handle(hctx, e)
+		handlerDuration.WithLabelValues(e.Type, name).Observe(time.Since(start).Seconds())
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		if err != nil {
+			handlerErrors.WithLabelValues(e.Type, name, errs.KindOf(err).String()).Inc()
+			p.logger.WithContext(ctx).Error("Error en handler", map[string]interface{}{
+				"error":      err.Error(),
 				"event_type": e.Type,
+				"handler":    name,
+				"kind":       errs.KindOf(err).String(),
 			})
-			return err
+			return &HandlerError{Handler: name, Err: err}
 		}
 	}
 
+	p.markProcessed(ctx, e)
 	return nil
 }
+
+// alreadyProcessed consulta el store de idempotencia. Ante un fallo del store se procesa
+// el evento: es preferible un duplicado a perder la notificación.
+func (p *Processor) alreadyProcessed(ctx context.Context, e *domain.Event) bool {
+	if p.dedupe == nil || e.ID == "" {
+		return false
+	}
+	seen, err := p.dedupe.Seen(ctx, e.ID)
+	if err != nil {
+		p.logger.WithContext(ctx).Warn("Fallo al consultar store de idempotencia", map[string]interface{}{
+			"error":    err.Error(),
+			"event_id": e.ID,
+		})
+		return false
+	}
+	return seen
+}
+
+func (p *Processor) markProcessed(ctx context.Context, e *domain.Event) {
+	if p.dedupe == nil || e.ID == "" {
+		return
+	}
+	if err := p.dedupe.MarkDone(ctx, e.ID); err != nil {
+		p.logger.WithContext(ctx).Warn("Fallo al registrar evento en store de idempotencia", map[string]interface{}{
+			"error":    err.Error(),
+			"event_id": e.ID,
+		})
+	}
+}
+
+// RetryPolicy expone la política de reintentos declarada en el registry
+func (p *Processor) RetryPolicy(eventType string) handler.RetryPolicy {
+	return p.registry.RetryPolicy(eventType)
+}
+
+// Handles indica si el tipo de evento tiene handlers registrados
+func (p *Processor) Handles(eventType string) bool {
+	return p.registry.Has(eventType)
+}
+
+// handlerName retorna un nombre legible del handler para logs y metadatos
+func handlerName(h handler.EventHandler) string {
+	return fmt.Sprintf("%T", h)
+}
@@ -0,0 +1,127 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/domain"
+	"github.com/andrew/orquestador-notificacion/internal/logger"
+	"github.com/andrew/orquestador-notificacion/internal/timer"
+)
+
+// TimerKindEvent identifica los timers de eventos programados (deliver_at / delay)
+const TimerKindEvent = "event"
+
+// WithTimers habilita los eventos programados: un evento con deliver_at o delay futuro
+// se guarda como timer y se procesa al vencer; los eventos posteriores pueden cancelarlo
+// (ver domain.Event.CancelOn).
+func WithTimers(s timer.Store) Option {
+	return func(p *Processor) {
+		p.timers = s
+	}
+}
+
+// schedule guarda el evento si está programado a futuro. Retorna true si quedó diferido.
+func (p *Processor) schedule(ctx context.Context, e *domain.Event) (bool, error) {
+	if p.timers == nil {
+		return false, nil
+	}
+	at, ok, err := e.ScheduledAt()
+	if err != nil {
+		return false, err
+	}
+	if !ok || !at.After(time.Now()) {
+		return false, nil
+	}
+
+	// El timer conserva la correlación para que los logs al vencer sigan el mismo flujo
+	if e.CorrelationID == "" {
+		e.CorrelationID = logger.CorrelationID(ctx)
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return false, err
+	}
+	// El ID es determinístico: una re-entrega del mensaje reemplaza el mismo timer
+	t := timer.Timer{
+		ID:      TimerKindEvent + ":" + e.ID,
+		Kind:    TimerKindEvent,
+		Key:     e.SubjectKey(),
+		DueAt:   at,
+		Payload: payload,
+	}
+	if err := p.timers.Add(ctx, t); err != nil {
+		return false, err
+	}
+
+	p.logger.WithContext(ctx).Info("Evento programado", map[string]interface{}{
+		"event_type": e.Type,
+		"event_id":   e.ID,
+		"subject":    t.Key,
+		"due_at":     at.UTC().Format(time.RFC3339),
+		"cancel_on":  e.CancelOn,
+	})
+	return true, nil
+}
+
+// cancelPending elimina los eventos programados del mismo sujeto que declararon
+// el tipo de este evento en su CancelOn
+func (p *Processor) cancelPending(ctx context.Context, e *domain.Event) {
+	if p.timers == nil {
+		return
+	}
+	key := e.SubjectKey()
+	if key == "" {
+		return
+	}
+	pending, err := p.timers.ByKey(ctx, key)
+	if err != nil {
+		p.logger.WithContext(ctx).Warn("Fallo al consultar eventos programados", map[string]interface{}{
+			"error":   err.Error(),
+			"subject": key,
+		})
+		return
+	}
+
+	for _, t := range pending {
+		if t.Kind != TimerKindEvent {
+			continue
+		}
+		var scheduled domain.Event
+		if err := json.Unmarshal(t.Payload, &scheduled); err != nil || !contains(scheduled.CancelOn, e.Type) {
+			continue
+		}
+		if err := p.timers.Remove(ctx, t.ID); err != nil {
+			p.logger.WithContext(ctx).Warn("Fallo al cancelar evento programado", map[string]interface{}{
+				"error":    err.Error(),
+				"timer_id": t.ID,
+			})
+			continue
+		}
+		p.logger.WithContext(ctx).Info("Evento programado cancelado", map[string]interface{}{
+			"event_type":   scheduled.Type,
+			"event_id":     scheduled.ID,
+			"cancelled_by": e.Type,
+			"subject":      key,
+		})
+	}
+}
+
+// FireTimer procesa un evento programado cuyo momento llegó (timer.HandlerFunc)
+func (p *Processor) FireTimer(ctx context.Context, t timer.Timer) error {
+	var e domain.Event
+	if err := json.Unmarshal(t.Payload, &e); err != nil {
+		return err
+	}
+	return p.dispatch(withCorrelation(ctx, &e), &e)
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+// Package progress permite a los handlers registrar los pasos ya completados de un evento
+// (ej: email enviado) para que un reintento solo repita los envíos que fallaron.
+package progress
+
+import (
+	"context"
+
+	"github.com/andrew/orquestador-notificacion/internal/idempotency"
+)
+
+type ctxKey struct{}
+
+type eventProgress struct {
+	store   idempotency.Store
+	eventID string
+}
+
+// WithEvent adjunta al contexto el store de progreso y el evento en proceso.
+// Lo llama el processor antes de invocar los handlers.
+func WithEvent(ctx context.Context, store idempotency.Store, eventID string) context.Context {
+	if store == nil || eventID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxKey{}, &eventProgress{store: store, eventID: eventID})
+}
+
+// Step ejecuta fn solo si el paso (canal + template) no fue completado en un intento anterior
+// del mismo evento, y lo marca como completado si fn termina sin error.
+// Sin progreso en el contexto, fn siempre se ejecuta. Un fallo al registrar el paso no se
+// propaga: el envío ya se hizo y reintentar el evento completo lo duplicaría.
+func Step(ctx context.Context, channel, template string, fn func() error) error {
+	done, err := IsDone(ctx, channel, template)
+	if err == nil && done {
+		return nil
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	_ = MarkDone(ctx, channel, template)
+	return nil
+}
+
+// IsDone indica si el paso ya fue completado para el evento del contexto
+func IsDone(ctx context.Context, channel, template string) (bool, error) {
+	p, ok := ctx.Value(ctxKey{}).(*eventProgress)
+	if !ok {
+		return false, nil
+	}
+	return p.store.Seen(ctx, stepKey(p.eventID, channel, template))
+}
+
+// MarkDone registra el paso como completado para el evento del contexto
+func MarkDone(ctx context.Context, channel, template string) error {
+	p, ok := ctx.Value(ctxKey{}).(*eventProgress)
+	if !ok {
+		return nil
+	}
+	return p.store.MarkDone(ctx, stepKey(p.eventID, channel, template))
+}
+
+func stepKey(eventID, channel, template string) string {
+	return "step:" + eventID + ":" + channel + ":" + template
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import "time"
+
+// bucket es un token bucket: Tokens disponibles a la fecha Last
+type bucket struct {
+	Tokens float64       `json:"tokens"`
+	Last   time.Time     `json:"last"`
+	Per    time.Duration `json:"per"`
+}
+
+// refill rellena el bucket hasta now. Un bucket nuevo (Last cero) empieza lleno.
+func (b bucket) refill(rate Rate, now time.Time) bucket {
+	capacity := float64(rate.Count)
+	if b.Last.IsZero() {
+		b.Tokens = capacity
+	} else if elapsed := now.Sub(b.Last); elapsed > 0 {
+		b.Tokens += float64(elapsed) / float64(perToken(rate))
+		if b.Tokens > capacity {
+			b.Tokens = capacity
+		}
+	}
+	b.Last = now
+	b.Per = rate.Per
+	return b
+}
+
+// wait retorna cuánto falta para que el bucket (ya rellenado) tenga un token
+func (b bucket) wait(rate Rate) time.Duration {
+	if b.Tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.Tokens) * float64(perToken(rate)))
+}
+
+func perToken(rate Rate) time.Duration {
+	return rate.Per / time.Duration(rate.Count)
+}
+
+// full indica si el bucket ya se habría rellenado por completo, en cuyo caso puede
+// descartarse: uno nuevo empieza lleno
+func (b bucket) full(now time.Time) bool {
+	return now.Sub(b.Last) >= b.Per
+}
+
+// takeAll consume un token de cada bucket pedido solo si todos tienen disponible. Retorna
+// los buckets actualizados, o el índice del primer pedido que bloquea y cuánto falta para
+// su próximo token; en ese caso buckets no se modifica.
+func takeAll(buckets map[string]bucket, reqs []Request, now time.Time) (map[string]bucket, int, time.Duration) {
+	taken := make(map[string]bucket, len(reqs))
+	for i, r := range reqs {
+		b, ok := taken[r.Key]
+		if !ok {
+			b = buckets[r.Key]
+		}
+		b = b.refill(r.Rate, now)
+		if b.Tokens < 1 {
+			return nil, i, b.wait(r.Rate)
+		}
+		b.Tokens--
+		taken[r.Key] = b
+	}
+	return taken, -1, 0
+}
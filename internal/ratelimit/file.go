@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/appendlog"
+)
+
+type record struct {
+	Key    string `json:"key"`
+	Bucket bucket `json:"bucket"`
+}
+
+// FileStore guarda los buckets en memoria y registra cada cambio en un log append-only
+// local (JSON por línea), para que los límites sobrevivan a un reinicio
+type FileStore struct {
+	mu      sync.Mutex
+	log     *appendlog.Log[record]
+	buckets map[string]bucket
+}
+
+// NewFileStore abre (o crea) el log de buckets y recupera su estado
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{buckets: make(map[string]bucket)}
+	log, err := appendlog.Open(path, "rate limit", func(rec record) {
+		s.buckets[rec.Key] = rec.Bucket
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.log = log
+	sweep(s.buckets, time.Now())
+	if err := s.rewrite(); err != nil {
+		log.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Take solo escribe en el log cuando consume tokens: un envío bloqueado no cambia el estado
+// (el relleno se recalcula a partir de Last)
+func (s *FileStore) Take(_ context.Context, reqs []Request, now time.Time) (bool, int, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	taken, blocked, wait := takeAll(s.buckets, reqs, now)
+	if blocked >= 0 {
+		return false, blocked, wait, nil
+	}
+	recs := make([]record, 0, len(taken))
+	for key, b := range taken {
+		recs = append(recs, record{Key: key, Bucket: b})
+	}
+	if err := s.log.Append(recs...); err != nil {
+		return false, -1, 0, err
+	}
+	for key, b := range taken {
+		s.buckets[key] = b
+	}
+
+	if s.log.ShouldCompact(len(s.buckets)) {
+		sweep(s.buckets, now)
+		if err := s.rewrite(); err != nil {
+			return true, -1, 0, err
+		}
+	}
+	return true, -1, 0, nil
+}
+
+// rewrite compacta el log dejando un registro por bucket. Requiere el lock tomado
+// (o ejecutarse durante la construcción).
+func (s *FileStore) rewrite() error {
+	recs := make([]record, 0, len(s.buckets))
+	for key, b := range s.buckets {
+		recs = append(recs, record{Key: key, Bucket: b})
+	}
+	return s.log.Rewrite(recs)
+}
+
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.log.Close()
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery es la cantidad de Take entre limpiezas de buckets llenos
+const sweepEvery = 1000
+
+// MemoryStore guarda los buckets en memoria (se pierden al reiniciar)
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]bucket
+	takes   int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]bucket)}
+}
+
+func (s *MemoryStore) Take(_ context.Context, reqs []Request, now time.Time) (bool, int, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	taken, blocked, wait := takeAll(s.buckets, reqs, now)
+	if blocked >= 0 {
+		return false, blocked, wait, nil
+	}
+	for key, b := range taken {
+		s.buckets[key] = b
+	}
+
+	s.takes++
+	if s.takes%sweepEvery == 0 {
+		sweep(s.buckets, now)
+	}
+	return true, -1, 0, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// sweep elimina los buckets llenos para acotar la memoria
+func sweep(buckets map[string]bucket, now time.Time) {
+	for key, b := range buckets {
+		if b.full(now) {
+			delete(buckets, key)
+		}
+	}
+}
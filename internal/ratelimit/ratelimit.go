@@ -0,0 +1,177 @@
+// Package ratelimit limita la cantidad de notificaciones por destinatario, usuario o
+// template con token buckets (ej: como máximo 3 password_recovery por email por hora).
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scope es la dimensión sobre la que se cuenta un límite
+type Scope string
+
+const (
+	ScopeRecipient Scope = "recipient"
+	ScopeUser      Scope = "user"
+	ScopeTemplate  Scope = "template"
+)
+
+// AnyTemplate hace que una regla aplique a todos los templates
+const AnyTemplate = "*"
+
+// Rate permite Count envíos por ventana Per (el bucket se rellena de forma continua)
+type Rate struct {
+	Count int
+	Per   time.Duration
+}
+
+func (r Rate) String() string {
+	return fmt.Sprintf("%d/%s", r.Count, r.Per)
+}
+
+// Rule es un límite para un template (o AnyTemplate) en un scope
+type Rule struct {
+	Template string
+	Scope    Scope
+	Rate     Rate
+}
+
+func (r Rule) String() string {
+	return fmt.Sprintf("%s.%s=%s", r.Template, r.Scope, r.Rate)
+}
+
+// Subject describe el envío que se quiere hacer
+type Subject struct {
+	Recipient string
+	UserID    string
+	Template  string
+}
+
+// Decision es el resultado de consultar el limitador
+type Decision struct {
+	Allowed bool
+	// Rule y RetryAfter indican el límite que bloqueó el envío
+	Rule       Rule
+	RetryAfter time.Duration
+}
+
+// Request pide un token del bucket Key, que se rellena según Rate
+type Request struct {
+	Key  string
+	Rate Rate
+}
+
+// Store guarda el estado de los buckets. Take consume un token de cada bucket pedido solo
+// si todos tienen disponible; si no, no consume ninguno y retorna el índice del primer
+// pedido que bloquea y cuánto falta para su próximo token.
+type Store interface {
+	Take(ctx context.Context, reqs []Request, now time.Time) (ok bool, blocked int, wait time.Duration, err error)
+	Close() error
+}
+
+// Limiter aplica las reglas configuradas sobre un Store
+type Limiter struct {
+	rules []Rule
+	store Store
+	now   func() time.Time
+}
+
+func NewLimiter(rules []Rule, store Store) *Limiter {
+	return &Limiter{rules: rules, store: store, now: time.Now}
+}
+
+// Allow consume un token de cada regla que aplica al envío, solo si ninguna lo bloquea:
+// un envío bloqueado no gasta el cupo de las demás reglas
+func (l *Limiter) Allow(ctx context.Context, s Subject) (Decision, error) {
+	var rules []Rule
+	var reqs []Request
+	for _, r := range l.rules {
+		key, ok := r.key(s)
+		if !ok {
+			continue
+		}
+		rules = append(rules, r)
+		reqs = append(reqs, Request{Key: key, Rate: r.Rate})
+	}
+	if len(reqs) == 0 {
+		return Decision{Allowed: true}, nil
+	}
+
+	ok, blocked, wait, err := l.store.Take(ctx, reqs, l.now())
+	if err != nil {
+		return Decision{}, err
+	}
+	if !ok {
+		return Decision{Rule: rules[blocked], RetryAfter: wait}, nil
+	}
+	return Decision{Allowed: true}, nil
+}
+
+// Rules retorna las reglas configuradas
+func (l *Limiter) Rules() []Rule {
+	return l.rules
+}
+
+// key arma la clave del bucket; false si la regla no aplica al envío
+func (r Rule) key(s Subject) (string, bool) {
+	if r.Template != AnyTemplate && r.Template != s.Template {
+		return "", false
+	}
+	switch r.Scope {
+	case ScopeRecipient:
+		if s.Recipient == "" {
+			return "", false
+		}
+		return r.Template + "|recipient|" + strings.ToLower(s.Recipient), true
+	case ScopeUser:
+		if s.UserID == "" {
+			return "", false
+		}
+		return r.Template + "|user|" + s.UserID, true
+	default:
+		return r.Template + "|template|" + s.Template, true
+	}
+}
+
+// ParseRules lee las reglas con el formato "template.scope=N/duración" separadas por ';',
+// por ejemplo "password_recovery.recipient=3/1h;*.user=50/24h"
+func ParseRules(spec string) ([]Rule, error) {
+	var rules []Rule
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		target, rate, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("límite %q: falta '='", entry)
+		}
+		dot := strings.LastIndexByte(target, '.')
+		if dot <= 0 {
+			return nil, fmt.Errorf("límite %q: se esperaba template.scope", entry)
+		}
+		template, scope := strings.TrimSpace(target[:dot]), Scope(strings.TrimSpace(target[dot+1:]))
+		if scope != ScopeRecipient && scope != ScopeUser && scope != ScopeTemplate {
+			return nil, fmt.Errorf("límite %q: scope %q desconocido (recipient, user o template)", entry, scope)
+		}
+
+		countRaw, perRaw, ok := strings.Cut(rate, "/")
+		if !ok {
+			return nil, fmt.Errorf("límite %q: se esperaba N/duración", entry)
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(countRaw))
+		if err != nil || count < 1 {
+			return nil, fmt.Errorf("límite %q: cantidad inválida", entry)
+		}
+		per, err := time.ParseDuration(strings.TrimSpace(perRaw))
+		if err != nil || per <= 0 {
+			return nil, fmt.Errorf("límite %q: duración inválida", entry)
+		}
+
+		rules = append(rules, Rule{Template: template, Scope: scope, Rate: Rate{Count: count, Per: per}})
+	}
+	return rules, nil
+}
@@ -0,0 +1,164 @@
+package ratelimit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("password_recovery.recipient=3/1h; *.user=50/24h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Rule{
+		{Template: "password_recovery", Scope: ScopeRecipient, Rate: Rate{Count: 3, Per: time.Hour}},
+		{Template: AnyTemplate, Scope: ScopeUser, Rate: Rate{Count: 50, Per: 24 * time.Hour}},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("reglas = %v, se esperaba %v", rules, want)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("regla %d = %v, se esperaba %v", i, rules[i], want[i])
+		}
+	}
+
+	for _, spec := range []string{"otp.recipient", "otp.recipient=3", "otp.planet=3/1h", "otp.recipient=0/1h"} {
+		if _, err := ParseRules(spec); err == nil {
+			t.Errorf("ParseRules(%q) no falló", spec)
+		}
+	}
+}
+
+// TestLimiterAllow recorre una secuencia de envíos; un envío bloqueado por una regla no
+// debe consumir el cupo de las demás
+func TestLimiterAllow(t *testing.T) {
+	rules := []Rule{
+		{Template: "otp", Scope: ScopeUser, Rate: Rate{Count: 3, Per: time.Hour}},
+		{Template: "otp", Scope: ScopeRecipient, Rate: Rate{Count: 1, Per: time.Hour}},
+	}
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	type step struct {
+		after     time.Duration
+		recipient string
+		want      bool
+		wantRule  Scope
+		wantWait  time.Duration
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "el bloqueo por destinatario no gasta el cupo del usuario",
+			steps: []step{
+				{0, "a@x.com", true, "", 0},
+				{0, "a@x.com", false, ScopeRecipient, time.Hour},
+				{0, "a@x.com", false, ScopeRecipient, time.Hour},
+				// El usuario solo gastó 1 de 3: los otros destinatarios pasan
+				{0, "b@x.com", true, "", 0},
+				{0, "c@x.com", true, "", 0},
+				{0, "d@x.com", false, ScopeUser, 20 * time.Minute},
+			},
+		},
+		{
+			name: "el bucket se rellena con el tiempo",
+			steps: []step{
+				{0, "a@x.com", true, "", 0},
+				{30 * time.Minute, "a@x.com", false, ScopeRecipient, 30 * time.Minute},
+				{time.Hour, "a@x.com", true, "", 0},
+			},
+		},
+		{
+			name: "otros templates no aplican",
+			steps: []step{
+				{0, "", true, "", 0},
+			},
+		},
+	}
+
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(*testing.T) Store { return NewMemoryStore() },
+		"file": func(t *testing.T) Store {
+			s, err := NewFileStore(filepath.Join(t.TempDir(), "ratelimit.log"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	}
+
+	for storeName, newStore := range stores {
+		for _, tt := range tests {
+			t.Run(storeName+"/"+tt.name, func(t *testing.T) {
+				store := newStore(t)
+				defer store.Close()
+				l := NewLimiter(rules, store)
+				for i, s := range tt.steps {
+					now := start.Add(s.after)
+					l.now = func() time.Time { return now }
+					subject := Subject{Recipient: s.recipient, UserID: "42", Template: "otp"}
+					if s.recipient == "" {
+						subject = Subject{Recipient: "a@x.com", UserID: "42", Template: "welcome"}
+					}
+					d, err := l.Allow(context.Background(), subject)
+					if err != nil {
+						t.Fatalf("paso %d: %v", i, err)
+					}
+					if d.Allowed != s.want {
+						t.Fatalf("paso %d: Allowed = %v, se esperaba %v", i, d.Allowed, s.want)
+					}
+					if !s.want && (d.Rule.Scope != s.wantRule || d.RetryAfter != s.wantWait) {
+						t.Errorf("paso %d: bloqueado por %s (espera %s), se esperaba %s (espera %s)",
+							i, d.Rule.Scope, d.RetryAfter, s.wantRule, s.wantWait)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestFileStoreSkipsDenied(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.log")
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	reqs := []Request{{Key: "otp|user|42", Rate: Rate{Count: 1, Per: time.Hour}}}
+
+	if ok, _, _, err := s.Take(context.Background(), reqs, now); !ok || err != nil {
+		t.Fatalf("primer Take = %v, %v", ok, err)
+	}
+	size := fileSize(t, path)
+	if ok, blocked, _, err := s.Take(context.Background(), reqs, now); ok || blocked != 0 || err != nil {
+		t.Fatalf("segundo Take = %v, %d, %v; se esperaba bloqueo del pedido 0", ok, blocked, err)
+	}
+	if got := fileSize(t, path); got != size {
+		t.Errorf("un Take bloqueado escribió en el log (%d -> %d bytes)", size, got)
+	}
+	s.Close()
+
+	// El consumo sobrevive al reinicio
+	s, err = NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if ok, _, _, _ := s.Take(context.Background(), reqs, now.Add(time.Minute)); ok {
+		t.Error("tras reabrir el bucket volvió a estar lleno")
+	}
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Size()
+}
@@ -0,0 +1,70 @@
+package routing
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/domain"
+)
+
+// Roots son las raíces de las rutas del documento del evento
+var Roots = []string{"event", "payload"}
+
+// Document arma la vista del evento sobre la que se resuelven las rutas de las reglas:
+//
+//	event.id, event.type, event.source, event.timestamp
+//	payload.<campo>[.<subcampo>...]
+func Document(e *domain.Event) (map[string]interface{}, error) {
+	payload := map[string]interface{}{}
+	if len(e.Payload) > 0 {
+		if err := e.DecodePayload(&payload); err != nil {
+			return nil, err
+		}
+	}
+	return map[string]interface{}{
+		"event": map[string]interface{}{
+			"id":        e.ID,
+			"type":      e.Type,
+			"source":    e.Source,
+			"timestamp": e.Timestamp.Format(time.RFC3339),
+		},
+		"payload": payload,
+	}, nil
+}
+
+// Lookup resuelve una ruta con puntos (ej: "payload.user.email") dentro del documento
+func Lookup(doc map[string]interface{}, path string) (interface{}, bool) {
+	var cur interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		cur, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// LookupString resuelve la ruta y la convierte a texto; retorna "" si no existe
+func LookupString(doc map[string]interface{}, path string) string {
+	v, ok := Lookup(doc, path)
+	if !ok || v == nil {
+		return ""
+	}
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		// Los números JSON se decodifican como float64; los enteros se muestran sin decimales
+		if t == float64(int64(t)) {
+			return fmt.Sprintf("%d", int64(t))
+		}
+		return fmt.Sprintf("%v", t)
+	default:
+		return fmt.Sprintf("%v", t)
+	}
+}
@@ -0,0 +1,141 @@
+// Package routing carga el archivo declarativo que asocia cada tipo de evento con las
+// notificaciones a enviar (canal, template, destinatario y datos).
+package routing
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/andrew/orquestador-notificacion/internal/domain"
+	"github.com/andrew/orquestador-notificacion/internal/expr"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule describe una notificación a enviar para un tipo de evento.
+// Recipient, UserID y los valores de Data son rutas sobre el documento del evento
+// (ver Document), por ejemplo "payload.email" o "event.source".
+// When es una condición opcional (ver paquete expr) que debe cumplirse para enviar.
+// Contacts asocia otros canales con la ruta de su destinatario, para las cadenas de fallback.
+type Rule struct {
+	Channel   string            `yaml:"channel"`
+	Template  string            `yaml:"template"`
+	Recipient string            `yaml:"recipient"`
+	UserID    string            `yaml:"user_id"`
+	Data      map[string]string `yaml:"data"`
+	Contacts  map[string]string `yaml:"contacts"`
+	When      string            `yaml:"when"`
+
+	cond *expr.Expr
+}
+
+// Matches evalúa la condición de la regla sobre el documento del evento.
+// Las condiciones se compilan en Validate; una regla sin condición siempre aplica.
+func (r Rule) Matches(doc map[string]interface{}) bool {
+	if r.cond == nil {
+		return true
+	}
+	return r.cond.Eval(doc)
+}
+
+// Config es el contenido del archivo de routing
+type Config struct {
+	Events map[string][]Rule `yaml:"events"`
+}
+
+// Load lee el archivo de routing (YAML o JSON) y valida sus reglas
+func Load(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("leer archivo de routing: %w", err)
+	}
+
+	// YAML es un superconjunto de JSON, por lo que el mismo parser acepta ambos formatos
+	var cfg Config
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("parsear archivo de routing %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("archivo de routing %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Validate verifica que cada regla tenga los campos obligatorios y canales conocidos, y
+// compila sus condiciones
+func (c *Config) Validate() error {
+	for _, eventType := range c.EventTypes() {
+		rules := c.Events[eventType]
+		for i, r := range rules {
+			if r.Channel == "" || r.Template == "" || r.Recipient == "" {
+				return fmt.Errorf("regla %s: channel, template y recipient son obligatorios", RuleRef(eventType, i, r))
+			}
+			if !domain.IsChannel(r.Channel) {
+				return fmt.Errorf("regla %s: canal %q desconocido (canales: %s)", RuleRef(eventType, i, r), r.Channel, strings.Join(domain.Channels, ", "))
+			}
+			for channel := range r.Contacts {
+				if !domain.IsChannel(channel) {
+					return fmt.Errorf("regla %s: canal %q desconocido en contacts (canales: %s)", RuleRef(eventType, i, r), channel, strings.Join(domain.Channels, ", "))
+				}
+			}
+			if err := r.checkPaths(); err != nil {
+				return fmt.Errorf("regla %s: %w", RuleRef(eventType, i, r), err)
+			}
+			if r.When == "" {
+				continue
+			}
+			cond, err := expr.Compile(r.When, Roots...)
+			if err != nil {
+				return fmt.Errorf("regla %s: condición %q inválida: %w", RuleRef(eventType, i, r), r.When, err)
+			}
+			rules[i].cond = cond
+		}
+	}
+	return nil
+}
+
+// checkPaths verifica que las rutas de la regla comiencen por una de las raíces del documento
+func (r Rule) checkPaths() error {
+	paths := []string{r.Recipient, r.UserID}
+	for _, p := range r.Data {
+		paths = append(paths, p)
+	}
+	for _, p := range r.Contacts {
+		paths = append(paths, p)
+	}
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		root, _, _ := strings.Cut(p, ".")
+		if !isRoot(root) {
+			return fmt.Errorf("ruta %q desconocida, debe comenzar por %s", p, strings.Join(Roots, ", "))
+		}
+	}
+	return nil
+}
+
+func isRoot(s string) bool {
+	for _, root := range Roots {
+		if root == s {
+			return true
+		}
+	}
+	return false
+}
+
+// EventTypes retorna los tipos de evento configurados, ordenados
+func (c *Config) EventTypes() []string {
+	types := make([]string, 0, len(c.Events))
+	for t := range c.Events {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// RuleRef identifica una regla en mensajes de error y logs, ej: USER_LOGIN[1] (SMS/login_alert)
+func RuleRef(eventType string, index int, r Rule) string {
+	return fmt.Sprintf("%s[%d] (%s/%s)", eventType, index, r.Channel, r.Template)
+}
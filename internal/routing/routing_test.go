@@ -0,0 +1,73 @@
+package routing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr string // vacío = válida
+	}{
+		{
+			name: "regla válida",
+			rule: Rule{Channel: "SMS", Template: "login_alert", Recipient: "payload.phone",
+				Contacts: map[string]string{"WHATSAPP": "payload.phone"}, When: `payload.phone != ""`},
+		},
+		{
+			name:    "sin recipient",
+			rule:    Rule{Channel: "EMAIL", Template: "login_alert"},
+			wantErr: "obligatorios",
+		},
+		{
+			name:    "canal desconocido",
+			rule:    Rule{Channel: "EMIAL", Template: "login_alert", Recipient: "payload.email"},
+			wantErr: `canal "EMIAL" desconocido`,
+		},
+		{
+			name: "canal desconocido en contacts",
+			rule: Rule{Channel: "SMS", Template: "login_alert", Recipient: "payload.phone",
+				Contacts: map[string]string{"sms": "payload.phone"}},
+			wantErr: `canal "sms" desconocido en contacts`,
+		},
+		{
+			name:    "ruta fuera del documento",
+			rule:    Rule{Channel: "EMAIL", Template: "login_alert", Recipient: "user.email"},
+			wantErr: `ruta "user.email" desconocida`,
+		},
+		{
+			name:    "condición con ruta fuera del documento",
+			rule:    Rule{Channel: "EMAIL", Template: "login_alert", Recipient: "payload.email", When: `source == "web"`},
+			wantErr: `ruta "source" desconocida`,
+		},
+		{
+			name:    "condición inválida",
+			rule:    Rule{Channel: "EMAIL", Template: "login_alert", Recipient: "payload.email", When: "payload.email =="},
+			wantErr: "condición",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Events: map[string][]Rule{"USER_LOGIN": {tt.rule}}}
+			err := cfg.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Validate: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("Validate = %v, se esperaba un error con %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadExample(t *testing.T) {
+	cfg, err := Load("../../config/routing.example.yaml")
+	if err != nil {
+		t.Fatalf("el ejemplo no valida: %v", err)
+	}
+	if len(cfg.Events) == 0 {
+		t.Fatal("el ejemplo no tiene reglas")
+	}
+}
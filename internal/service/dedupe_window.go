@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/idempotency"
+)
+
+// dedupeCapacity es la cantidad máxima de huellas recordadas por template
+const dedupeCapacity = 100000
+
+// DedupeWindow suprime las notificaciones idénticas de un template publicadas dentro de
+// Window. La huella se arma con destinatario, canal, template y los campos Fields de Data.
+type DedupeWindow struct {
+	Window time.Duration
+	Fields []string
+}
+
+// ParseDedupeWindows lee las ventanas por template con el formato
+// "template=ventana[:campo,campo];..." (ej: "login_alert=30s:user_id;welcome=1h")
+func ParseDedupeWindows(spec string) (map[string]DedupeWindow, error) {
+	windows := make(map[string]DedupeWindow)
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		template, rest, ok := strings.Cut(entry, "=")
+		template = strings.TrimSpace(template)
+		if !ok || template == "" {
+			return nil, fmt.Errorf("ventana de deduplicación %q: se esperaba template=ventana", entry)
+		}
+		windowRaw, fieldsRaw, _ := strings.Cut(rest, ":")
+		window, err := time.ParseDuration(strings.TrimSpace(windowRaw))
+		if err != nil || window <= 0 {
+			return nil, fmt.Errorf("ventana de deduplicación %q: duración inválida", entry)
+		}
+		var fields []string
+		for _, f := range strings.Split(fieldsRaw, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				fields = append(fields, f)
+			}
+		}
+		windows[template] = DedupeWindow{Window: window, Fields: fields}
+	}
+	return windows, nil
+}
+
+// dedupe recuerda las huellas publicadas recientemente, con un store por template
+// (cada template tiene su propia ventana)
+type dedupe struct {
+	mu      sync.Mutex
+	windows map[string]DedupeWindow
+	seen    map[string]*idempotency.MemoryStore
+}
+
+// WithDedupeWindows habilita la supresión de notificaciones idénticas por template
+func WithDedupeWindows(windows map[string]DedupeWindow) Option {
+	return func(s *userServiceImpl) {
+		d := &dedupe{windows: windows, seen: make(map[string]*idempotency.MemoryStore, len(windows))}
+		for template, w := range windows {
+			d.seen[template] = idempotency.NewMemoryStore(dedupeCapacity, w.Window)
+		}
+		s.dedupe = d
+	}
+}
+
+// reserve registra la huella de la notificación. Retorna false si ya se publicó una
+// idéntica dentro de la ventana; si no, una función para liberar la reserva cuando
+// la publicación falla.
+func (d *dedupe) reserve(ctx context.Context, n Notification) (bool, func()) {
+	noop := func() {}
+	if d == nil {
+		return true, noop
+	}
+	w, ok := d.windows[n.Template]
+	if !ok {
+		return true, noop
+	}
+	store := d.seen[n.Template]
+	key := fingerprint(n, w.Fields)
+
+	// El lock hace atómicos la consulta y el registro entre workers concurrentes
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if seen, _ := store.Seen(ctx, key); seen {
+		return false, noop
+	}
+	_ = store.MarkDone(ctx, key)
+	return true, func() { store.Forget(key) }
+}
+
+func fingerprint(n Notification, fields []string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s", strings.ToLower(n.To), n.Channel, n.Template)
+	for _, f := range fields {
+		fmt.Fprintf(h, "\x00%s=%v", f, n.Data[f])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
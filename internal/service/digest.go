@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/digest"
+	"github.com/andrew/orquestador-notificacion/internal/timer"
+)
+
+// TimerKindDigest identifica los timers que cierran un resumen al vencer su ventana
+const TimerKindDigest = "digest"
+
+// digester acumula las notificaciones de los templates con regla de resumen. La ventana
+// de cada resumen se controla con un timer, por lo que también sobrevive a reinicios.
+type digester struct {
+	rules  map[string]digest.Rule
+	store  digest.Store
+	timers timer.Store
+}
+
+// WithDigest acumula las notificaciones de los templates indicados (ej: login_alert) y las
+// envía como un único resumen al llegar a MaxItems o al vencer la ventana
+func WithDigest(rules map[string]digest.Rule, store digest.Store, timers timer.Store) Option {
+	return func(s *userServiceImpl) {
+		s.digest = &digester{rules: rules, store: store, timers: timers}
+	}
+}
+
+// digestTimer es el payload del timer de un resumen
+type digestTimer struct {
+	Key string `json:"key"`
+}
+
+// collect agrega la notificación a su resumen si el template tiene regla. Retorna true si
+// la notificación quedó acumulada (o se envió como parte de un resumen completo).
+func (s *userServiceImpl) collect(ctx context.Context, n Notification) (bool, error) {
+	if s.digest == nil {
+		return false, nil
+	}
+	rule, ok := s.digest.rules[n.Template]
+	if !ok {
+		return false, nil
+	}
+
+	now := s.now()
+	b, err := s.digest.store.Append(ctx, digest.Batch{
+		Key:      digest.BatchKey(n.UserID, n.To, n.Channel, n.Template),
+		UserID:   n.UserID,
+		Channel:  n.Channel,
+		Template: n.Template,
+		To:       n.To,
+	}, digest.Item{At: now, Data: n.Data})
+	if err != nil {
+		return false, err
+	}
+
+	// El timer se asegura en cada item y no solo en el primero: si su creación falla, el
+	// reintento del evento agrega otro item y vuelve a intentarlo
+	if err := s.ensureDigestTimer(ctx, b, rule); err != nil {
+		return false, err
+	}
+
+	if len(b.Items) >= rule.MaxItems {
+		// Si el envío falla el resumen se restaura y su timer (ya creado) lo reintenta al
+		// vencer la ventana
+		if err := s.FlushDigest(ctx, b.Key); err != nil {
+			s.logger.WithContext(ctx).Warn("Fallo al enviar resumen completo, se reintentará con su timer", map[string]interface{}{
+				"error":    err.Error(),
+				"template": n.Template,
+				"user_id":  n.UserID,
+			})
+		}
+		return true, nil
+	}
+	s.logger.WithContext(ctx).Debug("Notificación acumulada en resumen", map[string]interface{}{
+		"template": n.Template,
+		"user_id":  n.UserID,
+		"items":    len(b.Items),
+	})
+	return true, nil
+}
+
+// FlushDigest envía el resumen acumulado. Con un solo item se envía la notificación
+// original; con más, una única notificación con el template del resumen.
+func (s *userServiceImpl) FlushDigest(ctx context.Context, key string) error {
+	if s.digest == nil {
+		return nil
+	}
+	b, ok, err := s.digest.store.Take(ctx, key)
+	if err != nil || !ok {
+		return err
+	}
+	n := Notification{UserID: b.UserID, Channel: b.Channel, Template: b.Template, To: b.To}
+	if len(b.Items) == 1 {
+		n.Data = b.Items[0].Data
+	} else {
+		rule := s.digest.rules[b.Template]
+		items := make([]map[string]interface{}, len(b.Items))
+		for i, it := range b.Items {
+			items[i] = it.Data
+		}
+		n.Template = rule.DigestTemplate
+		n.Data = map[string]interface{}{
+			"user_id":  b.UserID,
+			"template": b.Template,
+			"count":    len(b.Items),
+			"first_at": b.Items[0].At.UTC().Format(time.RFC3339),
+			"last_at":  b.Items[len(b.Items)-1].At.UTC().Format(time.RFC3339),
+			"items":    items,
+		}
+	}
+
+	if err := s.deliver(ctx, n, passDigest); err != nil {
+		if restoreErr := s.digest.store.Restore(ctx, b); restoreErr != nil {
+			s.logger.WithContext(ctx).Error("Fallo al restaurar resumen no enviado", map[string]interface{}{
+				"error": restoreErr.Error(),
+				"key":   key,
+				"items": len(b.Items),
+			})
+		}
+		return err
+	}
+	// El resumen pudo cerrarse por tamaño antes de que venciera su timer
+	_ = s.digest.timers.Remove(ctx, TimerKindDigest+":"+key)
+
+	s.logger.WithContext(ctx).Info("Resumen de notificaciones enviado", map[string]interface{}{
+		"template": n.Template,
+		"user_id":  b.UserID,
+		"items":    len(b.Items),
+	})
+	return nil
+}
+
+// ensureDigestTimer programa el cierre del resumen al vencer su ventana si aún no tiene
+// timer. Si ya existe (ej: el resumen se está cerrando desde su propio timer) no se toca,
+// para no perder el backoff del scheduler.
+func (s *userServiceImpl) ensureDigestTimer(ctx context.Context, b digest.Batch, rule digest.Rule) error {
+	id := TimerKindDigest + ":" + b.Key
+	if _, ok, err := s.digest.timers.Get(ctx, id); err != nil || ok {
+		return err
+	}
+	payload, err := json.Marshal(digestTimer{Key: b.Key})
+	if err != nil {
+		return err
+	}
+	return s.digest.timers.Add(ctx, timer.Timer{
+		ID:      id,
+		Kind:    TimerKindDigest,
+		Key:     b.UserID,
+		DueAt:   b.OpenedAt.Add(rule.Window),
+		Payload: payload,
+	})
+}
+
+// DigestTimerHandler cierra los resúmenes cuya ventana venció
+func DigestTimerHandler(us UserService) timer.HandlerFunc {
+	return func(ctx context.Context, t timer.Timer) error {
+		var p digestTimer
+		if err := json.Unmarshal(t.Payload, &p); err != nil {
+			return err
+		}
+		return us.FlushDigest(ctx, p.Key)
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/digest"
+	"github.com/andrew/orquestador-notificacion/internal/logger"
+	"github.com/andrew/orquestador-notificacion/internal/timer"
+)
+
+// flakyTimers es un timer.Store en memoria cuyo Add falla mientras failAdd sea positivo
+type flakyTimers struct {
+	*timer.MemoryStore
+	failAdd int
+}
+
+func (s *flakyTimers) Add(ctx context.Context, t timer.Timer) error {
+	if s.failAdd > 0 {
+		s.failAdd--
+		return errors.New("disco lleno")
+	}
+	return s.MemoryStore.Add(ctx, t)
+}
+
+func newDigestService(t *testing.T, maxItems int) (*userServiceImpl, *fakeProducer, digest.Store, *flakyTimers) {
+	t.Helper()
+	producer := &fakeProducer{}
+	store := digest.NewMemoryStore()
+	timers := &flakyTimers{MemoryStore: timer.NewMemoryStore()}
+	rules := map[string]digest.Rule{
+		"login_alert": {DigestTemplate: "login_digest", MaxItems: maxItems, Window: time.Hour},
+	}
+	svc := NewUserService(producer, logger.New("[Test]"), WithDigest(rules, store, timers)).(*userServiceImpl)
+	return svc, producer, store, timers
+}
+
+func loginAlert(ip string) Notification {
+	return Notification{UserID: "42", Channel: "EMAIL", Template: "login_alert", To: "ana@example.com",
+		Data: map[string]interface{}{"ip": ip}}
+}
+
+func TestDigestBatching(t *testing.T) {
+	ctx := context.Background()
+	key := digest.BatchKey("42", "ana@example.com", "EMAIL", "login_alert")
+	timerID := TimerKindDigest + ":" + key
+
+	t.Run("se envía al llegar a max_items", func(t *testing.T) {
+		svc, producer, store, timers := newDigestService(t, 3)
+		for _, ip := range []string{"1.1.1.1", "2.2.2.2"} {
+			if err := svc.Deliver(ctx, loginAlert(ip)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if n := len(producer.published()); n != 0 {
+			t.Fatalf("se publicaron %d notificaciones antes de completar el resumen", n)
+		}
+		if _, ok, _ := timers.Get(ctx, timerID); !ok {
+			t.Fatal("el resumen abierto no tiene timer")
+		}
+
+		if err := svc.Deliver(ctx, loginAlert("3.3.3.3")); err != nil {
+			t.Fatal(err)
+		}
+		events := producer.published()
+		if len(events) != 1 || events[0].Template != "login_digest" || events[0].Data["count"] != 3 {
+			t.Fatalf("publicadas = %+v, se esperaba un login_digest con 3 items", events)
+		}
+		if store.Len() != 0 {
+			t.Error("el resumen enviado sigue en el store")
+		}
+		if _, ok, _ := timers.Get(ctx, timerID); ok {
+			t.Error("el timer del resumen enviado no se eliminó")
+		}
+	})
+
+	t.Run("la ventana cierra el resumen", func(t *testing.T) {
+		svc, producer, _, timers := newDigestService(t, 10)
+		if err := svc.Deliver(ctx, loginAlert("1.1.1.1")); err != nil {
+			t.Fatal(err)
+		}
+		tm, ok, _ := timers.Get(ctx, timerID)
+		if !ok {
+			t.Fatal("el resumen abierto no tiene timer")
+		}
+		if err := DigestTimerHandler(svc)(ctx, tm); err != nil {
+			t.Fatal(err)
+		}
+		// Con un solo item se envía la notificación original
+		events := producer.published()
+		if len(events) != 1 || events[0].Template != "login_alert" || events[0].Data["ip"] != "1.1.1.1" {
+			t.Fatalf("publicadas = %+v, se esperaba el login_alert original", events)
+		}
+	})
+
+	t.Run("max_items=1 con envío fallido queda con timer", func(t *testing.T) {
+		svc, producer, store, timers := newDigestService(t, 1)
+		producer.fail(errors.New("broker caído"))
+		if err := svc.Deliver(ctx, loginAlert("1.1.1.1")); err != nil {
+			t.Fatal(err)
+		}
+		if store.Len() != 1 {
+			t.Fatal("el resumen no enviado no se restauró")
+		}
+		tm, ok, _ := timers.Get(ctx, timerID)
+		if !ok {
+			t.Fatal("el resumen restaurado quedó sin timer")
+		}
+
+		producer.fail(nil)
+		if err := DigestTimerHandler(svc)(ctx, tm); err != nil {
+			t.Fatal(err)
+		}
+		if len(producer.published()) != 1 || store.Len() != 0 {
+			t.Error("el timer no envió el resumen restaurado")
+		}
+	})
+
+	t.Run("si falla la creación del timer, el reintento lo crea", func(t *testing.T) {
+		svc, _, store, timers := newDigestService(t, 10)
+		timers.failAdd = 1
+		if err := svc.Deliver(ctx, loginAlert("1.1.1.1")); err == nil {
+			t.Fatal("Deliver no retornó el error del timer")
+		}
+		// El consumer reintenta el evento: el item se agrega de nuevo
+		if err := svc.Deliver(ctx, loginAlert("1.1.1.1")); err != nil {
+			t.Fatal(err)
+		}
+		if store.Len() != 1 {
+			t.Fatal("el resumen no está en el store")
+		}
+		if _, ok, _ := timers.Get(ctx, timerID); !ok {
+			t.Fatal("el resumen quedó sin timer tras el reintento")
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/andrew/orquestador-notificacion/internal/contact"
+	"github.com/andrew/orquestador-notificacion/internal/preferences"
+)
+
+// ErrNoChannelAvailable indica que la notificación no se pudo enviar porque los canales
+// viables están fuera de servicio; reintentar más tarde puede tener éxito
+var ErrNoChannelAvailable = errors.New("ningún canal disponible para la notificación")
+
+// Motivos por los que se descarta un canal de la cadena
+const (
+	skipNoContact   = "sin dato de contacto"
+	skipUnavailable = "canal no disponible"
+)
+
+// ChannelHealth informa si un canal de salida está operativo
+type ChannelHealth interface {
+	Available(channel string) bool
+}
+
+// ChannelStatus es un ChannelHealth en memoria que se actualiza manualmente
+// (configuración, endpoint de administración o health checks)
+type ChannelStatus struct {
+	mu       sync.RWMutex
+	disabled map[string]bool
+}
+
+// NewChannelStatus crea el estado de canales con los canales indicados deshabilitados
+func NewChannelStatus(disabled []string) *ChannelStatus {
+	s := &ChannelStatus{disabled: make(map[string]bool, len(disabled))}
+	for _, ch := range disabled {
+		s.disabled[ch] = true
+	}
+	return s
+}
+
+func (s *ChannelStatus) Available(channel string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return !s.disabled[channel]
+}
+
+// SetAvailable habilita o deshabilita un canal
+func (s *ChannelStatus) SetAvailable(channel string, available bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if available {
+		delete(s.disabled, channel)
+	} else {
+		s.disabled[channel] = true
+	}
+}
+
+// fallback resuelve el canal a usar según las cadenas por template (ej: SMS → WHATSAPP → EMAIL)
+type fallback struct {
+	chains map[string][]string
+	health ChannelHealth
+}
+
+// WithFallback define cadenas de canales alternativos por template. Si el canal pedido no
+// tiene dato de contacto, está deshabilitado por el usuario o fuera de servicio, se usa el
+// siguiente canal de la cadena. health puede ser nil (todos los canales disponibles).
+func WithFallback(chains map[string][]string, health ChannelHealth) Option {
+	return func(s *userServiceImpl) {
+		s.fallback = &fallback{chains: chains, health: health}
+	}
+}
+
+// skippedChannel registra por qué un canal de la cadena no se usó
+type skippedChannel struct {
+	Channel string `json:"channel"`
+	Reason  string `json:"reason"`
+	err     error
+}
+
+// candidates retorna el canal pedido seguido de los canales posteriores de su cadena
+func (f *fallback) candidates(n Notification) []string {
+	if f == nil {
+		return []string{n.Channel}
+	}
+	chain := f.chains[n.Template]
+	for i, ch := range chain {
+		if ch == n.Channel {
+			return chain[i:]
+		}
+	}
+	return []string{n.Channel}
+}
+
+func (f *fallback) available(channel string) bool {
+	return f == nil || f.health == nil || f.health.Available(channel)
+}
+
+// selectChannel recorre los candidatos y retorna la notificación dirigida al primer canal
+// viable junto con los canales descartados. ok es false si ninguno es viable.
+func (s *userServiceImpl) selectChannel(n Notification, prefs *preferences.Preferences) (Notification, []skippedChannel, bool) {
+	var skipped []skippedChannel
+	for _, ch := range s.fallback.candidates(n) {
+		// Un usuario sin el dato (ej: sin teléfono) no es un error mientras otro canal de la
+		// cadena lo tenga (ver invalidRecipient)
+		to := n.contact(ch)
+		if strings.TrimSpace(to) == "" {
+			skipped = append(skipped, skippedChannel{Channel: ch, Reason: skipNoContact})
+			continue
+		}
+		if s.contacts != nil {
+			normalized, err := s.contacts.Normalize(ch, to)
+			if err != nil {
+				skipped = append(skipped, skippedChannel{Channel: ch, Reason: err.Error(), err: err})
+				continue
+			}
+			to = normalized
+		}
+		if ok, reason := s.prefPolicy.Allow(prefs, ch, n.Template); !ok {
+			skipped = append(skipped, skippedChannel{Channel: ch, Reason: reason})
+			continue
+		}
+		if !s.fallback.available(ch) {
+			skipped = append(skipped, skippedChannel{Channel: ch, Reason: skipUnavailable})
+			continue
+		}
+
+		selected := n
+		selected.Channel = ch
+		selected.To = to
+		return selected, skipped, true
+	}
+	return n, skipped, false
+}
+
+// contact retorna el destinatario de la notificación para el canal indicado
+func (n Notification) contact(channel string) string {
+	if channel == n.Channel && n.To != "" {
+		return n.To
+	}
+	return n.Contacts[channel]
+}
+
+// contactsFor arma los datos de contacto por canal a partir del email y el teléfono
+func contactsFor(email, phone string) map[string]string {
+	contacts := make(map[string]string, 3)
+	if email != "" {
+		contacts["EMAIL"] = email
+	}
+	if phone != "" {
+		contacts["SMS"] = phone
+		contacts["WHATSAPP"] = phone
+	}
+	return contacts
+}
+
+// anyUnavailable indica si algún canal se descartó solo por estar fuera de servicio
+func anyUnavailable(skipped []skippedChannel) bool {
+	for _, sk := range skipped {
+		if sk.Reason == skipUnavailable {
+			return true
+		}
+	}
+	return false
+}
+
+// invalidRecipient retorna el primer error de destinatario inválido de los descartes, o un
+// *contact.InvalidError si ningún canal tenía destinatario: la notificación no puede
+// entregarse y el evento debe ir al DLQ. Si algún canal se descartó por las preferencias
+// del usuario retorna nil (omisión esperada).
+func invalidRecipient(channel string, skipped []skippedChannel) error {
+	noContact := len(skipped) > 0
+	for _, sk := range skipped {
+		if sk.err != nil {
+			return sk.err
+		}
+		if sk.Reason != skipNoContact {
+			noContact = false
+		}
+	}
+	if noContact {
+		return &contact.InvalidError{Channel: channel, Reason: "sin destinatario en ningún canal"}
+	}
+	return nil
+}
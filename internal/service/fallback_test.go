@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/andrew/orquestador-notificacion/internal/contact"
+	"github.com/andrew/orquestador-notificacion/internal/domain"
+	"github.com/andrew/orquestador-notificacion/internal/errs"
+	"github.com/andrew/orquestador-notificacion/internal/logger"
+	"github.com/andrew/orquestador-notificacion/internal/preferences"
+)
+
+// fakeProducer registra los eventos publicados; si err no es nil, falla sin publicar
+type fakeProducer struct {
+	mu     sync.Mutex
+	events []domain.NotificationEvent
+	err    error
+}
+
+func (p *fakeProducer) Send(context.Context, []byte, []byte) error { return nil }
+
+func (p *fakeProducer) SendEvent(ctx context.Context, eventType, template, to string, data map[string]interface{}) error {
+	return p.PublishEvent(ctx, domain.NewNotificationEvent(eventType, template, to, data))
+}
+
+func (p *fakeProducer) PublishEvent(_ context.Context, e domain.NotificationEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.events = append(p.events, e)
+	return nil
+}
+
+func (p *fakeProducer) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+func (p *fakeProducer) published() []domain.NotificationEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]domain.NotificationEvent(nil), p.events...)
+}
+
+func TestDeliverContactValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		n             Notification
+		chains        map[string][]string
+		prefs         *preferences.Preferences
+		noValidation  bool
+		wantTo        string // vacío = no se publica
+		wantPermanent bool
+	}{
+		{
+			name:   "destinatario válido se normaliza",
+			n:      Notification{Channel: "SMS", Template: "login_alert", To: "300 123 4567"},
+			wantTo: "+573001234567",
+		},
+		{
+			name:          "sin teléfono y sin cadena: error permanente",
+			n:             Notification{Channel: "SMS", Template: "login_alert", To: ""},
+			wantPermanent: true,
+		},
+		{
+			name:          "solo espacios cuenta como vacío",
+			n:             Notification{Channel: "SMS", Template: "login_alert", To: "   "},
+			wantPermanent: true,
+		},
+		{
+			name:          "sin destinatario en ningún canal de la cadena: error permanente",
+			n:             Notification{Channel: "SMS", Template: "login_alert"},
+			chains:        map[string][]string{"login_alert": {"SMS", "EMAIL"}},
+			wantPermanent: true,
+		},
+		{
+			name:          "sin destinatario y sin validación de contactos: error permanente",
+			n:             Notification{Channel: "EMAIL", Template: "login_alert"},
+			noValidation:  true,
+			wantPermanent: true,
+		},
+		{
+			name:   "sin teléfono y email excluido por el usuario: se omite sin error",
+			n:      Notification{UserID: "42", Channel: "SMS", Template: "login_alert", Contacts: map[string]string{"EMAIL": "ana@example.com"}},
+			chains: map[string][]string{"login_alert": {"SMS", "EMAIL"}},
+			prefs:  &preferences.Preferences{UserID: "42", Channels: []string{"SMS"}},
+		},
+		{
+			name:   "sin teléfono con cadena: pasa al siguiente canal",
+			n:      Notification{Channel: "SMS", Template: "login_alert", Contacts: map[string]string{"EMAIL": "Ana@Example.COM"}},
+			chains: map[string][]string{"login_alert": {"SMS", "EMAIL"}},
+			wantTo: "Ana@example.com",
+		},
+		{
+			name:          "teléfono mal formado: error permanente",
+			n:             Notification{Channel: "SMS", Template: "login_alert", To: "12ab"},
+			wantPermanent: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			producer := &fakeProducer{}
+			var opts []Option
+			if !tt.noValidation {
+				opts = append(opts, WithContactValidation(contact.NewNormalizer("CO")))
+			}
+			if tt.chains != nil {
+				opts = append(opts, WithFallback(tt.chains, nil))
+			}
+			if tt.prefs != nil {
+				store := preferences.NewMemoryStore()
+				if err := store.Put(context.Background(), *tt.prefs); err != nil {
+					t.Fatal(err)
+				}
+				opts = append(opts, WithPreferences(store, nil))
+			}
+			svc := NewUserService(producer, logger.New("[Test]"), opts...)
+
+			err := svc.Deliver(context.Background(), tt.n)
+			if tt.wantPermanent {
+				var invalid *contact.InvalidError
+				if !errs.IsPermanent(err) || !errors.As(err, &invalid) {
+					t.Fatalf("Deliver = %v, se esperaba un *contact.InvalidError permanente", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Deliver: %v", err)
+			}
+
+			events := producer.published()
+			if tt.wantTo == "" {
+				if len(events) != 0 {
+					t.Fatalf("se publicaron %d notificaciones, se esperaba ninguna", len(events))
+				}
+				return
+			}
+			if len(events) != 1 || events[0].To != tt.wantTo {
+				t.Fatalf("publicadas = %+v, se esperaba una a %q", events, tt.wantTo)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+
+	"github.com/andrew/orquestador-notificacion/internal/domain"
+	"github.com/andrew/orquestador-notificacion/internal/i18n"
+	"github.com/andrew/orquestador-notificacion/internal/preferences"
+)
+
+// localizer resuelve el locale de cada notificación y agrega sus mensajes traducidos
+type localizer struct {
+	bundle        *i18n.Bundle
+	defaultLocale string
+}
+
+// WithLocales habilita los catálogos de mensajes. defaultLocale se usa cuando ni el evento
+// ni las preferencias del usuario declaran un locale. bundle puede ser nil. Las variantes
+// de template por locale solo se resuelven si además se usa WithTemplates.
+func WithLocales(bundle *i18n.Bundle, defaultLocale string) Option {
+	return func(s *userServiceImpl) {
+		s.locales = &localizer{bundle: bundle, defaultLocale: i18n.Normalize(defaultLocale)}
+	}
+}
+
+// resolveLocale elige el locale: el del evento, el idioma de las preferencias o el por defecto
+func (s *userServiceImpl) resolveLocale(ctx context.Context, prefs *preferences.Preferences) string {
+	if locale := i18n.FromContext(ctx); locale != "" {
+		return locale
+	}
+	if prefs != nil && prefs.Language != "" {
+		return i18n.Normalize(prefs.Language)
+	}
+	if s.locales != nil {
+		return s.locales.defaultLocale
+	}
+	return ""
+}
+
+// localize resuelve la variante del template para el locale (welcome.es-CO → welcome.es →
+// welcome), registra el locale resuelto en el evento y agrega los mensajes del catálogo
+// en data["messages"]
+func (s *userServiceImpl) localize(event *domain.NotificationEvent, locale string) {
+	if locale == "" {
+		return
+	}
+	base := event.Template
+	event.Locale = locale
+	if s.renderer != nil {
+		for _, candidate := range i18n.Chain(locale) {
+			if s.renderer.registry.Has(base + "." + candidate) {
+				event.Template = base + "." + candidate
+				event.Locale = candidate
+				break
+			}
+		}
+	}
+
+	if s.locales == nil || s.locales.bundle == nil {
+		return
+	}
+	messages := s.locales.bundle.Messages(locale, base)
+	if len(messages) == 0 {
+		return
+	}
+	// Copia para no modificar el mapa de datos del llamador
+	data := make(map[string]interface{}, len(event.Data)+1)
+	for k, v := range event.Data {
+		data[k] = v
+	}
+	data["messages"] = messages
+	event.Data = data
+}
@@ -1,8 +1,14 @@
 package service
 
-import "context"
+import (
+	"context"
+
+	"github.com/andrew/orquestador-notificacion/internal/domain"
+)
 
 type Producer interface {
 	Send(ctx context.Context, key []byte, value []byte) error
 	SendEvent(ctx context.Context, eventType, template, to string, data map[string]interface{}) error
+	// PublishEvent publica un evento ya construido (con contenido renderizado, locale, etc.)
+	PublishEvent(ctx context.Context, event domain.NotificationEvent) error
 }
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/logger"
+	"github.com/andrew/orquestador-notificacion/internal/preferences"
+	"github.com/andrew/orquestador-notificacion/internal/timer"
+	"github.com/google/uuid"
+)
+
+// TimerKindNotification identifica los timers de notificaciones diferidas
+const TimerKindNotification = "notification"
+
+// quietHours difiere las notificaciones no urgentes que caen en las horas de silencio del
+// usuario, guardándolas como timers que se liberan cuando la ventana termina
+type quietHours struct {
+	timers     timer.Store
+	deferrable map[string]bool
+}
+
+// WithQuietHours habilita las horas de silencio para los templates indicados
+// (ej: welcome, account_verified). Los demás templates (OTP, alertas de seguridad) no se difieren.
+func WithQuietHours(timers timer.Store, deferrable []string) Option {
+	return func(s *userServiceImpl) {
+		q := &quietHours{timers: timers, deferrable: make(map[string]bool, len(deferrable))}
+		for _, t := range deferrable {
+			q.deferrable[t] = true
+		}
+		s.quietHours = q
+	}
+}
+
+// deferUntil retorna hasta cuándo diferir la notificación, si corresponde
+func (q *quietHours) deferUntil(prefs *preferences.Preferences, template string, now time.Time) (time.Time, bool) {
+	if prefs == nil || !q.deferrable[template] {
+		return time.Time{}, false
+	}
+	return prefs.QuietUntil(now)
+}
+
+func (q *quietHours) schedule(ctx context.Context, log *logger.Logger, n Notification, until time.Time) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	t := timer.Timer{
+		ID:      quietHoursTimerID(ctx, n),
+		Kind:    TimerKindNotification,
+		DueAt:   until,
+		Payload: payload,
+	}
+	if err := q.timers.Add(ctx, t); err != nil {
+		log.Error("Fallo al diferir notificación por horas de silencio", map[string]interface{}{
+			"error":    err.Error(),
+			"template": n.Template,
+			"user_id":  n.UserID,
+		})
+		return err
+	}
+
+	log.Info("Notificación diferida por horas de silencio", map[string]interface{}{
+		"channel":  n.Channel,
+		"template": n.Template,
+		"user_id":  n.UserID,
+		"until":    until.UTC().Format(time.RFC3339),
+		"timer_id": t.ID,
+	})
+	return nil
+}
+
+// quietHoursTimerID deriva el ID del timer del evento en proceso, el canal y el template:
+// si el evento se reintenta, el timer se reemplaza en lugar de duplicar la notificación.
+// Fuera de un evento (sin ID en el contexto) no hay reintentos y se usa un ID aleatorio.
+func quietHoursTimerID(ctx context.Context, n Notification) string {
+	eventID := logger.EventID(ctx)
+	if eventID == "" {
+		eventID = uuid.New().String()
+	}
+	return TimerKindNotification + ":" + eventID + ":" + n.Channel + ":" + n.Template
+}
+
+// NotificationTimerHandler libera las notificaciones diferidas cuando vence su timer
+func NotificationTimerHandler(us UserService) timer.HandlerFunc {
+	return func(ctx context.Context, t timer.Timer) error {
+		var n Notification
+		if err := json.Unmarshal(t.Payload, &n); err != nil {
+			return err
+		}
+		return us.DeliverDeferred(ctx, n)
+	}
+}
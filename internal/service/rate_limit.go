@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+
+	"github.com/andrew/orquestador-notificacion/internal/ratelimit"
+)
+
+// WithRateLimit limita los envíos por destinatario, usuario y template. Un envío bloqueado
+// se suprime (no es un error ni se reintenta) y queda registrado en el log.
+func WithRateLimit(l *ratelimit.Limiter) Option {
+	return func(s *userServiceImpl) {
+		s.limiter = l
+	}
+}
+
+// rateLimited consume los tokens del envío y retorna true si debe suprimirse.
+// Ante un fallo del store se permite el envío.
+func (s *userServiceImpl) rateLimited(ctx context.Context, n Notification) bool {
+	if s.limiter == nil {
+		return false
+	}
+	d, err := s.limiter.Allow(ctx, ratelimit.Subject{
+		Recipient: n.To,
+		UserID:    n.UserID,
+		Template:  n.Template,
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).Warn("Fallo al consultar el limitador de tasa", map[string]interface{}{
+			"error":    err.Error(),
+			"template": n.Template,
+			"user_id":  n.UserID,
+		})
+		return false
+	}
+	if d.Allowed {
+		return false
+	}
+
+	s.logger.WithContext(ctx).Warn("Notificación suprimida por límite de tasa", map[string]interface{}{
+		"channel":     n.Channel,
+		"template":    n.Template,
+		"to":          n.To,
+		"user_id":     n.UserID,
+		"limit":       d.Rule.String(),
+		"retry_after": d.RetryAfter.String(),
+	})
+	return true
+}
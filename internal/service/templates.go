@@ -0,0 +1,36 @@
+package service
+
+import (
+	"github.com/andrew/orquestador-notificacion/internal/domain"
+	"github.com/andrew/orquestador-notificacion/internal/templates"
+)
+
+// renderer renderiza las notificaciones cuyos templates están cargados localmente
+type renderer struct {
+	registry       *templates.Registry
+	includeContent bool
+}
+
+// WithTemplates valida los datos de cada notificación contra su template local antes de
+// publicarla; con includeContent el contenido renderizado viaja en el NotificationEvent.
+// Los templates que no están en el registry se publican sin validar, como antes.
+func WithTemplates(reg *templates.Registry, includeContent bool) Option {
+	return func(s *userServiceImpl) {
+		s.renderer = &renderer{registry: reg, includeContent: includeContent}
+	}
+}
+
+// render agrega el contenido al evento. Un dato requerido faltante es un error permanente.
+func (r *renderer) render(event *domain.NotificationEvent) error {
+	if r == nil {
+		return nil
+	}
+	content, ok, err := r.registry.Render(event.Template, event.Data)
+	if err != nil || !ok {
+		return err
+	}
+	if r.includeContent {
+		event.Content = content
+	}
+	return nil
+}
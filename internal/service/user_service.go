@@ -2,79 +2,136 @@ package service
 
 import (
 	"context"
+	"strconv"
+	"time"
 
+	"github.com/andrew/orquestador-notificacion/internal/contact"
+	"github.com/andrew/orquestador-notificacion/internal/domain"
 	"github.com/andrew/orquestador-notificacion/internal/logger"
+	"github.com/andrew/orquestador-notificacion/internal/metrics"
+	"github.com/andrew/orquestador-notificacion/internal/preferences"
+	"github.com/andrew/orquestador-notificacion/internal/ratelimit"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Métricas de notificaciones expuestas en /metrics; template es el nombre pedido, sin variante de locale
+var (
+	notificationsPublished = metrics.NewCounter("orchestrator_notifications_published_total",
+		"Notificaciones publicadas por canal y template", "channel", "template")
+	publishFailures = metrics.NewCounter("orchestrator_notification_publish_failures_total",
+		"Fallos al publicar notificaciones por canal y template", "channel", "template")
+)
+
+var tracer = otel.Tracer("github.com/andrew/orquestador-notificacion/internal/service")
+
 type UserService interface {
 	OnUserRegistered(ctx context.Context, id int, email, name, phone, url string) error
 	SendNotification(ctx context.Context, id int, email, name, phone, channel, template string) error
 	SendOtpRecovery(ctx context.Context, id int, email, name, url string) error
 	OnUserVerified(ctx context.Context, id int, email, name, phone string) error
+	// Deliver publica una notificación ya resuelta (usado por los handlers de routing)
+	Deliver(ctx context.Context, n Notification) error
+	// DeliverDeferred publica una notificación diferida cuyo momento de envío ya llegó
+	DeliverDeferred(ctx context.Context, n Notification) error
+	// FlushDigest envía el resumen acumulado con la clave indicada
+	FlushDigest(ctx context.Context, key string) error
+	// UpdatePreferences guarda las preferencias de notificación de un usuario
+	UpdatePreferences(ctx context.Context, prefs preferences.Preferences) error
+}
+
+// Notification es una notificación lista para publicar
+type Notification struct {
+	UserID   string                 `json:"user_id"`
+	Channel  string                 `json:"channel"`
+	Template string                 `json:"template"`
+	To       string                 `json:"to"`
+	Data     map[string]interface{} `json:"data"`
+	// Locale se resuelve en el primer paso y se conserva en notificaciones diferidas
+	Locale string `json:"locale,omitempty"`
+	// CorrelationID es el del evento de origen; se conserva en notificaciones diferidas
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// Contacts son los destinatarios por canal para las cadenas de fallback (ej: SMS → teléfono)
+	Contacts map[string]string `json:"contacts,omitempty"`
 }
 
 type userServiceImpl struct {
-	producer Producer // usa la interfaz, no la implementación concreta
-	logger   *logger.Logger
+	producer    Producer // usa la interfaz, no la implementación concreta
+	logger      *logger.Logger
+	preferences preferences.Store
+	prefPolicy  preferences.Policy
+	quietHours  *quietHours
+	fallback    *fallback
+	contacts    *contact.Normalizer
+	limiter     *ratelimit.Limiter
+	dedupe      *dedupe
+	digest      *digester
+	renderer    *renderer
+	locales     *localizer
+	now         func() time.Time
 }
 
-func NewUserService(producer Producer, log *logger.Logger) UserService {
-	return &userServiceImpl{producer: producer, logger: log}
+// Option configura aspectos opcionales del servicio
+type Option func(*userServiceImpl)
+
+// WithPreferences hace que el servicio consulte las preferencias del usuario antes de publicar.
+// Los templates de mandatory (ej: password_recovery) se envían aunque el usuario haya hecho opt-out.
+func WithPreferences(store preferences.Store, mandatory []string) Option {
+	return func(s *userServiceImpl) {
+		s.preferences = store
+		s.prefPolicy = preferences.NewPolicy(mandatory)
+	}
 }
 
-func (s *userServiceImpl) OnUserRegistered(ctx context.Context, id int, email, name, phone, url string) error {
-	data := map[string]interface{}{
-		"user_id": id,
-		"name":    name,
-		"phone":   phone,
-		"url":     url,
+// WithContactValidation valida y normaliza el destinatario de cada canal (email, E.164).
+// Un canal sin destinatario se omite. Si ningún canal es viable y alguno tenía un
+// destinatario mal formado (o ninguno tenía destinatario), Deliver retorna un
+// *contact.InvalidError, que es permanente: el evento va al DLQ sin reintentos.
+func WithContactValidation(n *contact.Normalizer) Option {
+	return func(s *userServiceImpl) {
+		s.contacts = n
 	}
+}
 
-	err := s.producer.SendEvent(ctx, "EMAIL", "welcome", email, data)
-	if err != nil {
-		s.logger.Error("Fallo al enviar notificación de bienvenida", map[string]interface{}{
-			"error": err.Error(),
-			"user_id": id,
-			"email": email,
-		})
-		return err
+func NewUserService(producer Producer, log *logger.Logger, opts ...Option) UserService {
+	s := &userServiceImpl{producer: producer, logger: log, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
+}
 
-	s.logger.Info("Evento de notificación de bienvenida publicado", map[string]interface{}{
-		"type":     "EMAIL",
-		"template": "welcome",
-		"to":       email,
-		"user_id":  id,
+func (s *userServiceImpl) OnUserRegistered(ctx context.Context, id int, email, name, phone, url string) error {
+	return s.Deliver(ctx, Notification{
+		UserID:   strconv.Itoa(id),
+		Channel:  "EMAIL",
+		Template: "welcome",
+		To:       email,
+		Data: map[string]interface{}{
+			"user_id": id,
+			"name":    name,
+			"phone":   phone,
+			"url":     url,
+		},
+		Contacts: contactsFor(email, phone),
 	})
-	return nil
 }
 
 func (s *userServiceImpl) SendNotification(ctx context.Context, id int, email, name, phone, channel, template string) error {
-	to := chooseTarget(channel, email, phone)
-	data := map[string]interface{}{
-		"user_id": id,
-		"name":    name,
-		"phone":   phone,
-	}
-
-	err := s.producer.SendEvent(ctx, channel, template, to, data)
-	if err != nil {
-		s.logger.Error("Fallo al enviar notificación", map[string]interface{}{
-			"error":    err.Error(),
-			"channel":  channel,
-			"template": template,
-			"user_id":  id,
-		})
-		return err
-	}
-
-	s.logger.Info("Notificación enviada exitosamente", map[string]interface{}{
-		"channel":  channel,
-		"template": template,
-		"to":       to,
-		"user_id":  id,
+	return s.Deliver(ctx, Notification{
+		UserID:   strconv.Itoa(id),
+		Channel:  channel,
+		Template: template,
+		To:       chooseTarget(channel, email, phone),
+		Data: map[string]interface{}{
+			"user_id": id,
+			"name":    name,
+			"phone":   phone,
+		},
+		Contacts: contactsFor(email, phone),
 	})
-	return nil
 }
 
 func chooseTarget(channel, email, phone string) string {
@@ -85,51 +142,241 @@ func chooseTarget(channel, email, phone string) string {
 }
 
 func (s *userServiceImpl) SendOtpRecovery(ctx context.Context, id int, email, name, url string) error {
-	data := map[string]interface{}{
-		"user_id": id,
-		"name":    name,
-		"url":     url,
+	return s.Deliver(ctx, Notification{
+		UserID:   strconv.Itoa(id),
+		Channel:  "EMAIL",
+		Template: "password_recovery",
+		To:       email,
+		Data: map[string]interface{}{
+			"user_id": id,
+			"name":    name,
+			"url":     url,
+		},
+		Contacts: contactsFor(email, ""),
+	})
+}
+
+func (s *userServiceImpl) OnUserVerified(ctx context.Context, id int, email, name, phone string) error {
+	return s.Deliver(ctx, Notification{
+		UserID:   strconv.Itoa(id),
+		Channel:  "EMAIL",
+		Template: "account_verified",
+		To:       email,
+		Data: map[string]interface{}{
+			"user_id": id,
+			"name":    name,
+			"phone":   phone,
+		},
+		Contacts: contactsFor(email, phone),
+	})
+}
+
+// Deliver aplica las políticas del usuario y publica la notificación.
+// Una notificación descartada o diferida por política no es un error.
+func (s *userServiceImpl) Deliver(ctx context.Context, n Notification) error {
+	return s.deliver(ctx, n, passNew)
+}
+
+func (s *userServiceImpl) DeliverDeferred(ctx context.Context, n Notification) error {
+	return s.deliver(ctx, n, passDeferred)
+}
+
+// pass indica de dónde llega la notificación, para no aplicar dos veces la misma política
+type pass int
+
+const (
+	passNew      pass = iota // notificación de un evento: todas las políticas
+	passDigest               // resumen cerrado: ya se deduplicó y acumuló
+	passDeferred             // liberada al terminar las horas de silencio
+)
+
+func (s *userServiceImpl) deliver(ctx context.Context, n Notification, p pass) error {
+	// Una notificación diferida recupera la correlación del evento que la originó
+	if n.CorrelationID == "" {
+		n.CorrelationID = logger.CorrelationID(ctx)
+	} else if logger.CorrelationID(ctx) == "" {
+		ctx = logger.WithCorrelationID(ctx, n.CorrelationID)
 	}
+	prefs := s.loadPreferences(ctx, n.UserID)
+	selected, skipped, ok := s.selectChannel(n, prefs)
+	if !ok {
+		s.logger.WithContext(ctx).Info("Notificación omitida: ningún canal viable", map[string]interface{}{
+			"channel":  n.Channel,
+			"template": n.Template,
+			"user_id":  n.UserID,
+			"skipped":  skipped,
+		})
+		if anyUnavailable(skipped) {
+			return ErrNoChannelAvailable
+		}
+		return invalidRecipient(n.Channel, skipped)
+	}
+	if len(skipped) > 0 {
+		s.logger.WithContext(ctx).Info("Notificación redirigida a canal alternativo", map[string]interface{}{
+			"requested": n.Channel,
+			"channel":   selected.Channel,
+			"template":  n.Template,
+			"user_id":   n.UserID,
+			"skipped":   skipped,
+		})
+	}
+	n = selected
+	if n.Locale == "" {
+		n.Locale = s.resolveLocale(ctx, prefs)
+	}
+
+	release := func() {}
+	if p == passNew {
+		var fresh bool
+		fresh, release = s.dedupe.reserve(ctx, n)
+		if !fresh {
+			s.logger.WithContext(ctx).Info("Notificación duplicada dentro de la ventana, se omite", map[string]interface{}{
+				"channel":  n.Channel,
+				"template": n.Template,
+				"user_id":  n.UserID,
+			})
+			return nil
+		}
+
+		collected, err := s.collect(ctx, n)
+		if err != nil {
+			release()
+			return err
+		}
+		if collected {
+			return nil
+		}
+	}
+
+	if p != passDeferred && s.quietHours != nil {
+		if until, ok := s.quietHours.deferUntil(prefs, n.Template, s.now()); ok {
+			if err := s.quietHours.schedule(ctx, s.logger.WithContext(ctx), n, until); err != nil {
+				release()
+				return err
+			}
+			return nil
+		}
+	}
+
+	if s.rateLimited(ctx, n) {
+		return nil
+	}
+
+	if err := s.publish(ctx, n); err != nil {
+		release()
+		return err
+	}
+	return nil
+}
 
-	err := s.producer.SendEvent(ctx, "EMAIL", "password_recovery", email, data)
+func (s *userServiceImpl) publish(ctx context.Context, n Notification) (err error) {
+	event := domain.NewNotificationEvent(n.Channel, n.Template, n.To, n.Data)
+	s.localize(&event, n.Locale)
+	event.CorrelationID = logger.CorrelationID(ctx)
+
+	// El span se propaga en los headers del mensaje hacia el servicio de envío
+	ctx, span := tracer.Start(ctx, "publish "+n.Template,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("notification.id", event.ID),
+			attribute.String("notification.channel", n.Channel),
+			attribute.String("notification.template", event.Template),
+		))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if err := s.renderer.render(&event); err != nil {
+		s.logger.WithContext(ctx).Error("Fallo al renderizar notificación", map[string]interface{}{
+			"error":    err.Error(),
+			"template": n.Template,
+			"user_id":  n.UserID,
+		})
+		return err
+	}
+
+	err = s.producer.PublishEvent(ctx, event)
 	if err != nil {
-		s.logger.Error("Fallo al enviar notificación de recuperación de contraseña", map[string]interface{}{
-			"error":   err.Error(),
-			"user_id": id,
-			"email":   email,
+		publishFailures.WithLabelValues(n.Channel, n.Template).Inc()
+		s.logger.WithContext(ctx).Error("Fallo al enviar notificación", map[string]interface{}{
+			"error":    err.Error(),
+			"channel":  n.Channel,
+			"template": n.Template,
+			"user_id":  n.UserID,
 		})
 		return err
 	}
 
-	s.logger.Info("Notificación de recuperación de contraseña enviada", map[string]interface{}{
-		"to":      email,
-		"user_id": id,
+	notificationsPublished.WithLabelValues(n.Channel, n.Template).Inc()
+	s.logger.WithContext(ctx).Info("Notificación enviada exitosamente", map[string]interface{}{
+		"channel":  n.Channel,
+		"template": n.Template,
+		"to":       n.To,
+		"user_id":  n.UserID,
 	})
 	return nil
 }
 
-func (s *userServiceImpl) OnUserVerified(ctx context.Context, id int, email, name, phone string) error {
-	data := map[string]interface{}{
-		"user_id": id,
-		"name":    name,
-		"phone":   phone,
+// loadPreferences consulta el store de preferencias. Ante un fallo del store se continúa sin
+// preferencias: es preferible una notificación no deseada a perder una alerta.
+func (s *userServiceImpl) loadPreferences(ctx context.Context, userID string) *preferences.Preferences {
+	if s.preferences == nil || userID == "" {
+		return nil
+	}
+	prefs, ok, err := s.preferences.Get(ctx, userID)
+	if err != nil {
+		s.logger.WithContext(ctx).Warn("Fallo al consultar preferencias del usuario", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		return nil
+	}
+	if !ok {
+		return nil
 	}
+	return prefs
+}
 
-	err := s.producer.SendEvent(ctx, "EMAIL", "account_verified", email, data)
+func (s *userServiceImpl) UpdatePreferences(ctx context.Context, prefs preferences.Preferences) error {
+	if s.preferences == nil {
+		s.logger.WithContext(ctx).Warn("Preferencias recibidas sin store configurado, se ignoran", map[string]interface{}{
+			"user_id": prefs.UserID,
+		})
+		return nil
+	}
+
+	// Los eventos pueden llegar desordenados entre reintentos: no pisar una versión más nueva
+	current, ok, err := s.preferences.Get(ctx, prefs.UserID)
 	if err != nil {
-		s.logger.Error("Fallo al enviar notificación de cuenta verificada", map[string]interface{}{
+		return err
+	}
+	if ok && !prefs.UpdatedAt.IsZero() && current.UpdatedAt.After(prefs.UpdatedAt) {
+		s.logger.WithContext(ctx).Info("Preferencias obsoletas, se conservan las actuales", map[string]interface{}{
+			"user_id":    prefs.UserID,
+			"current_at": current.UpdatedAt.Format(time.RFC3339),
+			"event_at":   prefs.UpdatedAt.Format(time.RFC3339),
+		})
+		return nil
+	}
+
+	if err := s.preferences.Put(ctx, prefs); err != nil {
+		s.logger.WithContext(ctx).Error("Fallo al guardar preferencias del usuario", map[string]interface{}{
 			"error":   err.Error(),
-			"user_id": id,
-			"email":   email,
+			"user_id": prefs.UserID,
 		})
 		return err
 	}
 
-	s.logger.Info("Notificación de cuenta verificada enviada", map[string]interface{}{
-		"type":     "EMAIL",
-		"template": "account_verified",
-		"to":       email,
-		"user_id":  id,
+	s.logger.WithContext(ctx).Info("Preferencias del usuario actualizadas", map[string]interface{}{
+		"user_id":   prefs.UserID,
+		"channels":  prefs.Channels,
+		"opt_out":   prefs.OptOutTemplates,
+		"language":  prefs.Language,
+		"time_zone": prefs.TimeZone,
 	})
 	return nil
 }
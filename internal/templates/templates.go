@@ -0,0 +1,238 @@
+// Package templates carga y renderiza localmente las versiones de cada template de
+// notificación (asunto, HTML, texto y SMS), para validarlas al arrancar y poder incluir
+// el contenido renderizado en el NotificationEvent.
+//
+// Estructura del directorio: <dir>/<template>/v<N>.yaml, por ejemplo
+//
+//	templates/welcome/v1.yaml
+//	templates/welcome/v2.yaml
+//
+// con el contenido:
+//
+//	required: [name, url]
+//	subject: "Bienvenido, {{.name}}"
+//	html: "<p>Hola {{.name}}, activa tu cuenta en <a href=\"{{.url}}\">este enlace</a></p>"
+//	text: "Hola {{.name}}, activa tu cuenta en {{.url}}"
+//	sms: "Activa tu cuenta: {{.url}}"
+//
+// La versión activa de cada template es la mayor.
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/andrew/orquestador-notificacion/internal/domain"
+	"github.com/andrew/orquestador-notificacion/internal/errs"
+	"gopkg.in/yaml.v3"
+)
+
+var versionFile = regexp.MustCompile(`^v(\d+)\.ya?ml$`)
+
+// source es el contenido de un archivo de versión
+type source struct {
+	Required []string `yaml:"required"`
+	Subject  string   `yaml:"subject"`
+	HTML     string   `yaml:"html"`
+	Text     string   `yaml:"text"`
+	SMS      string   `yaml:"sms"`
+}
+
+// Version es una versión compilada de un template
+type Version struct {
+	Name     string
+	Number   int
+	Required []string
+
+	subject *texttemplate.Template
+	html    *htmltemplate.Template
+	text    *texttemplate.Template
+	sms     *texttemplate.Template
+}
+
+// Registry contiene las versiones de todos los templates cargados
+type Registry struct {
+	versions map[string][]*Version // ordenadas por número de versión
+}
+
+// Load lee y compila todos los templates del directorio. Cada versión se valida
+// renderizándola con sus claves requeridas: un template que usa una clave no declarada
+// en required falla al arrancar y no en el primer envío.
+func Load(dir string) (*Registry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("leer directorio de templates: %w", err)
+	}
+
+	r := &Registry{versions: make(map[string][]*Version)}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		files, err := os.ReadDir(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("leer template %s: %w", name, err)
+		}
+		for _, f := range files {
+			m := versionFile.FindStringSubmatch(f.Name())
+			if f.IsDir() || m == nil {
+				continue
+			}
+			number, _ := strconv.Atoi(m[1])
+			path := filepath.Join(dir, name, f.Name())
+			v, err := loadVersion(path, name, number)
+			if err != nil {
+				return nil, err
+			}
+			r.versions[name] = append(r.versions[name], v)
+		}
+		sort.Slice(r.versions[name], func(i, j int) bool {
+			return r.versions[name][i].Number < r.versions[name][j].Number
+		})
+	}
+	return r, nil
+}
+
+func loadVersion(path, name string, number int) (*Version, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("leer template %s: %w", path, err)
+	}
+	var src source
+	if err := yaml.Unmarshal(raw, &src); err != nil {
+		return nil, fmt.Errorf("parsear template %s: %w", path, err)
+	}
+	if src.Subject == "" && src.HTML == "" && src.Text == "" && src.SMS == "" {
+		return nil, fmt.Errorf("template %s: no define subject, html, text ni sms", path)
+	}
+
+	v := &Version{Name: name, Number: number, Required: src.Required}
+	id := fmt.Sprintf("%s.v%d", name, number)
+	if v.subject, err = parseText(id+".subject", src.Subject); err != nil {
+		return nil, fmt.Errorf("template %s: subject: %w", path, err)
+	}
+	if v.text, err = parseText(id+".text", src.Text); err != nil {
+		return nil, fmt.Errorf("template %s: text: %w", path, err)
+	}
+	if v.sms, err = parseText(id+".sms", src.SMS); err != nil {
+		return nil, fmt.Errorf("template %s: sms: %w", path, err)
+	}
+	if src.HTML != "" {
+		if v.html, err = htmltemplate.New(id + ".html").Option("missingkey=error").Parse(src.HTML); err != nil {
+			return nil, fmt.Errorf("template %s: html: %w", path, err)
+		}
+	}
+
+	sample := make(map[string]interface{}, len(src.Required))
+	for _, key := range src.Required {
+		sample[key] = "x"
+	}
+	if _, err := v.render(sample); err != nil {
+		return nil, fmt.Errorf("template %s: usa datos no declarados en required: %w", path, err)
+	}
+	return v, nil
+}
+
+func parseText(id, src string) (*texttemplate.Template, error) {
+	if src == "" {
+		return nil, nil
+	}
+	return texttemplate.New(id).Option("missingkey=error").Parse(src)
+}
+
+// Names retorna los templates cargados, ordenados
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.versions))
+	for name := range r.versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has indica si el template está cargado
+func (r *Registry) Has(name string) bool {
+	return len(r.versions[name]) > 0
+}
+
+// Render renderiza la versión activa del template. ok es false si el template no está
+// cargado; la falta de datos requeridos es un error permanente.
+func (r *Registry) Render(name string, data map[string]interface{}) (*domain.Content, bool, error) {
+	versions := r.versions[name]
+	if len(versions) == 0 {
+		return nil, false, nil
+	}
+	c, err := versions[len(versions)-1].Render(data)
+	return c, true, err
+}
+
+// RenderVersion renderiza una versión específica (ej: para previsualizar una versión nueva)
+func (r *Registry) RenderVersion(name string, number int, data map[string]interface{}) (*domain.Content, error) {
+	for _, v := range r.versions[name] {
+		if v.Number == number {
+			return v.Render(data)
+		}
+	}
+	return nil, fmt.Errorf("template %s: versión %d no encontrada", name, number)
+}
+
+// Render valida los datos requeridos y renderiza la versión
+func (v *Version) Render(data map[string]interface{}) (*domain.Content, error) {
+	var missing []string
+	for _, key := range v.Required {
+		if _, ok := data[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, errs.NewPermanent(fmt.Errorf("template %s v%d: faltan datos requeridos: %s",
+			v.Name, v.Number, strings.Join(missing, ", ")))
+	}
+	c, err := v.render(data)
+	if err != nil {
+		return nil, errs.NewPermanent(err)
+	}
+	return c, nil
+}
+
+func (v *Version) render(data map[string]interface{}) (*domain.Content, error) {
+	c := &domain.Content{Version: v.Number}
+	var err error
+	if c.Subject, err = execText(v.subject, data); err != nil {
+		return nil, err
+	}
+	if c.Text, err = execText(v.text, data); err != nil {
+		return nil, err
+	}
+	if c.SMS, err = execText(v.sms, data); err != nil {
+		return nil, err
+	}
+	if v.html != nil {
+		var buf bytes.Buffer
+		if err := v.html.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		c.HTML = buf.String()
+	}
+	return c, nil
+}
+
+func execText(t *texttemplate.Template, data map[string]interface{}) (string, error) {
+	if t == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
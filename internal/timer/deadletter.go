@@ -0,0 +1,58 @@
+package timer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/appendlog"
+)
+
+// deadTimer es una línea del archivo de timers descartados
+type deadTimer struct {
+	Timer    Timer     `json:"timer"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// DeadLetter guarda los timers que el scheduler descarta (error permanente, sin handler o
+// intentos agotados) en un log append-only local, para revisarlos o reinyectarlos a mano
+type DeadLetter struct {
+	mu  sync.Mutex
+	log *appendlog.Log[deadTimer]
+	n   int
+}
+
+// OpenDeadLetter abre (o crea) el archivo de timers descartados
+func OpenDeadLetter(path string) (*DeadLetter, error) {
+	d := &DeadLetter{}
+	log, err := appendlog.Open(path, "timers descartados", func(deadTimer) { d.n++ })
+	if err != nil {
+		return nil, err
+	}
+	d.log = log
+	return d, nil
+}
+
+// Add registra el timer descartado junto con el error de su último intento
+func (d *DeadLetter) Add(t Timer, cause error, now time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.log.Append(deadTimer{Timer: t, Error: cause.Error(), FailedAt: now.UTC()}); err != nil {
+		return err
+	}
+	d.n++
+	return nil
+}
+
+// Len retorna la cantidad de timers descartados registrados en el archivo
+func (d *DeadLetter) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.n
+}
+
+func (d *DeadLetter) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.log.Close()
+}
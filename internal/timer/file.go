@@ -0,0 +1,123 @@
+package timer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/appendlog"
+)
+
+const (
+	opAdd    = "add"
+	opRemove = "remove"
+)
+
+type record struct {
+	Op    string `json:"op"`
+	Timer *Timer `json:"timer,omitempty"`
+	ID    string `json:"id,omitempty"`
+}
+
+// FileStore guarda los timers en un log append-only local (JSON por línea) y los mantiene
+// en memoria; al abrirse reconstruye los pendientes.
+type FileStore struct {
+	mu     sync.Mutex
+	log    *appendlog.Log[record]
+	timers map[string]Timer
+}
+
+// NewFileStore abre (o crea) el log de timers y recupera los pendientes
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{timers: make(map[string]Timer)}
+	log, err := appendlog.Open(path, "timers", s.apply)
+	if err != nil {
+		return nil, err
+	}
+	s.log = log
+	if err := s.rewrite(); err != nil {
+		log.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) apply(rec record) {
+	switch rec.Op {
+	case opAdd:
+		if rec.Timer != nil {
+			s.timers[rec.Timer.ID] = *rec.Timer
+		}
+	case opRemove:
+		delete(s.timers, rec.ID)
+	}
+}
+
+func (s *FileStore) Add(_ context.Context, t Timer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.log.Append(record{Op: opAdd, Timer: &t}); err != nil {
+		return err
+	}
+	s.timers[t.ID] = t
+	return nil
+}
+
+func (s *FileStore) Due(_ context.Context, now time.Time) ([]Timer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return dueTimers(s.timers, now), nil
+}
+
+func (s *FileStore) Get(_ context.Context, id string) (Timer, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.timers[id]
+	return t, ok, nil
+}
+
+func (s *FileStore) ByKey(_ context.Context, key string) ([]Timer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return timersByKey(s.timers, key), nil
+}
+
+func (s *FileStore) Remove(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.timers[id]; !ok {
+		return nil
+	}
+	if err := s.log.Append(record{Op: opRemove, ID: id}); err != nil {
+		return err
+	}
+	delete(s.timers, id)
+
+	if s.log.ShouldCompact(len(s.timers)) {
+		return s.rewrite()
+	}
+	return nil
+}
+
+func (s *FileStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.timers)
+}
+
+// rewrite compacta el log dejando solo los timers pendientes. Requiere el lock tomado
+// (o ejecutarse durante la construcción).
+func (s *FileStore) rewrite() error {
+	recs := make([]record, 0, len(s.timers))
+	for _, t := range s.timers {
+		t := t
+		recs = append(recs, record{Op: opAdd, Timer: &t})
+	}
+	return s.log.Rewrite(recs)
+}
+
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.log.Close()
+}
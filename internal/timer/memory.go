@@ -0,0 +1,83 @@
+package timer
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore guarda los timers en memoria (se pierden al reiniciar)
+type MemoryStore struct {
+	mu     sync.Mutex
+	timers map[string]Timer
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{timers: make(map[string]Timer)}
+}
+
+func (s *MemoryStore) Add(_ context.Context, t Timer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timers[t.ID] = t
+	return nil
+}
+
+func (s *MemoryStore) Due(_ context.Context, now time.Time) ([]Timer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return dueTimers(s.timers, now), nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, id string) (Timer, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.timers[id]
+	return t, ok, nil
+}
+
+func (s *MemoryStore) ByKey(_ context.Context, key string) ([]Timer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return timersByKey(s.timers, key), nil
+}
+
+func (s *MemoryStore) Remove(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.timers, id)
+	return nil
+}
+
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.timers)
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func dueTimers(timers map[string]Timer, now time.Time) []Timer {
+	var due []Timer
+	for _, t := range timers {
+		if !t.DueAt.After(now) {
+			due = append(due, t)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].DueAt.Before(due[j].DueAt) })
+	return due
+}
+
+func timersByKey(timers map[string]Timer, key string) []Timer {
+	var out []Timer
+	for _, t := range timers {
+		if t.Key == key {
+			out = append(out, t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].DueAt.Before(out[j].DueAt) })
+	return out
+}
@@ -0,0 +1,217 @@
+package timer
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/errs"
+	"github.com/andrew/orquestador-notificacion/internal/logger"
+)
+
+const (
+	defaultMaxAttempts   = 10
+	defaultRetryDelay    = time.Minute
+	defaultMaxRetryDelay = time.Hour
+)
+
+// HandlerFunc ejecuta un timer vencido
+type HandlerFunc func(ctx context.Context, t Timer) error
+
+// Scheduler revisa periódicamente el store y ejecuta los timers vencidos con el handler
+// registrado para su Kind. Un timer que falla se reprograma con backoff exponencial desde
+// retryDelay (o el RetryAfter de un error Throttled); uno con error permanente, sin handler
+// o que agotó maxAttempts se descarta y queda registrado en el DeadLetter.
+type Scheduler struct {
+	store         Store
+	handlers      map[string]HandlerFunc
+	logger        *logger.Logger
+	interval      time.Duration
+	retryDelay    time.Duration
+	maxRetryDelay time.Duration
+	maxAttempts   int
+	deadLetter    *DeadLetter
+}
+
+// Option configura aspectos opcionales del Scheduler
+type Option func(*Scheduler)
+
+// WithMaxAttempts define cuántas ejecuciones fallidas se toleran antes de descartar el timer
+func WithMaxAttempts(n int) Option {
+	return func(s *Scheduler) {
+		if n > 0 {
+			s.maxAttempts = n
+		}
+	}
+}
+
+// WithDeadLetter registra en d los timers descartados; sin él solo quedan en el log
+func WithDeadLetter(d *DeadLetter) Option {
+	return func(s *Scheduler) {
+		s.deadLetter = d
+	}
+}
+
+func NewScheduler(store Store, interval time.Duration, log *logger.Logger, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		store:         store,
+		handlers:      make(map[string]HandlerFunc),
+		logger:        log,
+		interval:      interval,
+		retryDelay:    defaultRetryDelay,
+		maxRetryDelay: defaultMaxRetryDelay,
+		maxAttempts:   defaultMaxAttempts,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Handle registra el handler de un tipo de timer. Debe llamarse antes de Run.
+func (s *Scheduler) Handle(kind string, fn HandlerFunc) {
+	s.handlers[kind] = fn
+}
+
+// Run ejecuta el ciclo del scheduler hasta que el contexto termine
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.fireDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) fireDue(ctx context.Context) {
+	now := time.Now()
+	due, err := s.store.Due(ctx, now)
+	if err != nil {
+		s.logger.Error("Fallo al consultar timers vencidos", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	for _, t := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		s.fire(ctx, t, now)
+	}
+}
+
+func (s *Scheduler) fire(ctx context.Context, t Timer, now time.Time) {
+	// La lista de vencidos se tomó antes de empezar a disparar: el timer pudo cancelarse
+	// (ej: cancel_on) o reprogramarse mientras se ejecutaban los anteriores
+	cur, ok, err := s.store.Get(ctx, t.ID)
+	if err != nil {
+		s.logger.Error("Fallo al consultar timer vencido", map[string]interface{}{
+			"timer_id": t.ID,
+			"error":    err.Error(),
+		})
+		return
+	}
+	if !ok || cur.DueAt.After(now) {
+		s.logger.Debug("Timer cancelado o reprogramado antes de dispararse, se omite", map[string]interface{}{
+			"timer_id": t.ID,
+			"kind":     t.Kind,
+		})
+		return
+	}
+	t = cur
+
+	fn, ok := s.handlers[t.Kind]
+	if !ok {
+		s.discard(ctx, t, errors.New("timer sin handler registrado"), now)
+		return
+	}
+
+	err = fn(ctx, t)
+	if err == nil {
+		s.remove(ctx, t)
+		return
+	}
+	if errs.IsPermanent(err) {
+		s.discard(ctx, t, err, now)
+		return
+	}
+
+	t.Attempts++
+	if t.Attempts >= s.maxAttempts {
+		s.discard(ctx, t, err, now)
+		return
+	}
+
+	delay := s.backoff(t.Attempts)
+	if wait, ok := errs.RetryAfter(err); ok && wait > delay {
+		delay = wait
+	}
+	s.logger.Warn("Fallo al ejecutar timer, se reprograma", map[string]interface{}{
+		"timer_id": t.ID,
+		"kind":     t.Kind,
+		"error":    err.Error(),
+		"attempts": t.Attempts,
+		"retry_at": now.Add(delay).Format(time.RFC3339),
+	})
+	s.reschedule(ctx, t, now.Add(delay))
+}
+
+// backoff retorna la espera tras el intento fallido número attempts: retryDelay, el doble
+// en cada intento, hasta maxRetryDelay
+func (s *Scheduler) backoff(attempts int) time.Duration {
+	delay := s.retryDelay
+	for i := 1; i < attempts && delay < s.maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.maxRetryDelay {
+		delay = s.maxRetryDelay
+	}
+	return delay
+}
+
+// discard saca el timer del store y lo registra en el DeadLetter. Si no puede registrarse,
+// el timer se reprograma para no perderlo.
+func (s *Scheduler) discard(ctx context.Context, t Timer, cause error, now time.Time) {
+	s.logger.Error("Timer descartado", map[string]interface{}{
+		"timer_id": t.ID,
+		"kind":     t.Kind,
+		"error":    cause.Error(),
+		"attempts": t.Attempts,
+	})
+	if s.deadLetter != nil {
+		if err := s.deadLetter.Add(t, cause, now); err != nil {
+			s.logger.Error("Fallo al registrar timer descartado, se reprograma", map[string]interface{}{
+				"timer_id": t.ID,
+				"error":    err.Error(),
+			})
+			s.reschedule(ctx, t, now.Add(s.maxRetryDelay))
+			return
+		}
+	}
+	s.remove(ctx, t)
+}
+
+func (s *Scheduler) reschedule(ctx context.Context, t Timer, dueAt time.Time) {
+	t.DueAt = dueAt
+	if err := s.store.Add(ctx, t); err != nil {
+		s.logger.Error("Fallo al reprogramar timer", map[string]interface{}{
+			"timer_id": t.ID,
+			"error":    err.Error(),
+		})
+	}
+}
+
+func (s *Scheduler) remove(ctx context.Context, t Timer) {
+	if err := s.store.Remove(ctx, t.ID); err != nil {
+		s.logger.Error("Fallo al eliminar timer ejecutado", map[string]interface{}{
+			"timer_id": t.ID,
+			"error":    err.Error(),
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package timer
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrew/orquestador-notificacion/internal/errs"
+	"github.com/andrew/orquestador-notificacion/internal/logger"
+)
+
+func TestSchedulerBackoff(t *testing.T) {
+	s := NewScheduler(NewMemoryStore(), time.Second, logger.New("[Test]"))
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{7, time.Hour},
+		{9, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := s.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %s, se esperaba %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestSchedulerFire(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	transient := errors.New("proveedor caído")
+
+	tests := []struct {
+		name         string
+		kind         string
+		attempts     int // intentos fallidos previos del timer
+		err          error
+		wantPending  bool
+		wantDueAt    time.Time
+		wantAttempts int
+		wantDead     int
+	}{
+		{name: "éxito", kind: "test", wantPending: false},
+		{name: "falla transitoria: se reprograma", kind: "test", err: transient,
+			wantPending: true, wantDueAt: now.Add(time.Minute), wantAttempts: 1},
+		{name: "falla con backoff", kind: "test", attempts: 2, err: transient,
+			wantPending: true, wantDueAt: now.Add(4 * time.Minute), wantAttempts: 3},
+		{name: "throttled respeta RetryAfter", kind: "test", err: errs.NewThrottled(transient, 10*time.Minute),
+			wantPending: true, wantDueAt: now.Add(10 * time.Minute), wantAttempts: 1},
+		{name: "intentos agotados: se descarta", kind: "test", attempts: 4, err: transient, wantDead: 1},
+		{name: "error permanente: se descarta", kind: "test", err: errs.NewPermanent(transient), wantDead: 1},
+		{name: "sin handler: se descarta", kind: "otro", wantDead: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryStore()
+			dead, err := OpenDeadLetter(filepath.Join(t.TempDir(), "timers.dead.log"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer dead.Close()
+
+			s := NewScheduler(store, time.Second, logger.New("[Test]"), WithMaxAttempts(5), WithDeadLetter(dead))
+			s.Handle("test", func(context.Context, Timer) error { return tt.err })
+
+			tm := Timer{ID: "t1", Kind: tt.kind, DueAt: now.Add(-time.Second), Attempts: tt.attempts}
+			if err := store.Add(ctx, tm); err != nil {
+				t.Fatal(err)
+			}
+			s.fire(ctx, tm, now)
+
+			pending, _ := store.Due(ctx, now.Add(24*time.Hour))
+			if got := len(pending) > 0; got != tt.wantPending {
+				t.Fatalf("pendiente = %v, se esperaba %v", got, tt.wantPending)
+			}
+			if tt.wantPending {
+				if !pending[0].DueAt.Equal(tt.wantDueAt) {
+					t.Errorf("DueAt = %s, se esperaba %s", pending[0].DueAt, tt.wantDueAt)
+				}
+				if pending[0].Attempts != tt.wantAttempts {
+					t.Errorf("Attempts = %d, se esperaba %d", pending[0].Attempts, tt.wantAttempts)
+				}
+			}
+			if n := dead.Len(); n != tt.wantDead {
+				t.Errorf("descartados = %d, se esperaba %d", n, tt.wantDead)
+			}
+		})
+	}
+}
+
+func TestDeadLetterReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timers.dead.log")
+	dead, err := OpenDeadLetter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dead.Add(Timer{ID: "t1", Kind: "test"}, errors.New("falla"), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	dead.Close()
+
+	dead, err = OpenDeadLetter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dead.Close()
+	if n := dead.Len(); n != 1 {
+		t.Errorf("descartados tras reabrir = %d, se esperaba 1", n)
+	}
+}
+
+// TestSchedulerSkipsCancelled verifica que un timer cancelado o reprogramado después de
+// tomar la lista de vencidos no se dispare
+func TestSchedulerSkipsCancelled(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	past := time.Now().Add(-time.Minute)
+	for _, id := range []string{"a", "b", "c"} {
+		if err := store.Add(ctx, Timer{ID: id, Kind: "test", DueAt: past}); err != nil {
+			t.Fatal(err)
+		}
+		past = past.Add(time.Second)
+	}
+
+	var fired []string
+	s := NewScheduler(store, time.Second, logger.New("[Test]"))
+	s.Handle("test", func(ctx context.Context, tm Timer) error {
+		fired = append(fired, tm.ID)
+		if tm.ID == "a" {
+			// El primero cancela al segundo y pospone al tercero
+			store.Remove(ctx, "b")
+			store.Add(ctx, Timer{ID: "c", Kind: "test", DueAt: time.Now().Add(time.Hour)})
+		}
+		return nil
+	})
+	s.fireDue(ctx)
+
+	if len(fired) != 1 || fired[0] != "a" {
+		t.Fatalf("disparados = %v, se esperaba solo [a]", fired)
+	}
+	if _, ok, _ := store.Get(ctx, "c"); !ok {
+		t.Error("el timer reprogramado se eliminó")
+	}
+}
@@ -0,0 +1,35 @@
+// Package timer guarda acciones diferidas (notificaciones en horas de silencio, eventos
+// programados) y las dispara cuando vencen, sobreviviendo a reinicios del proceso.
+package timer
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Timer es una acción pendiente. Kind selecciona el handler que la ejecuta y Payload
+// contiene sus datos serializados. Key agrupa timers relacionados (ej: los de un mismo
+// usuario) para poder buscarlos y cancelarlos. Attempts cuenta las ejecuciones fallidas.
+type Timer struct {
+	ID       string          `json:"id"`
+	Kind     string          `json:"kind"`
+	Key      string          `json:"key,omitempty"`
+	DueAt    time.Time       `json:"due_at"`
+	Payload  json.RawMessage `json:"payload"`
+	Attempts int             `json:"attempts,omitempty"`
+}
+
+// Store persiste los timers pendientes. Add con un ID existente lo reemplaza.
+type Store interface {
+	Add(ctx context.Context, t Timer) error
+	// Due retorna los timers vencidos a la fecha indicada, ordenados por vencimiento
+	Due(ctx context.Context, now time.Time) ([]Timer, error)
+	// Get retorna el timer pendiente con el ID indicado, si existe
+	Get(ctx context.Context, id string) (Timer, bool, error)
+	// ByKey retorna los timers pendientes con la Key indicada
+	ByKey(ctx context.Context, key string) ([]Timer, error)
+	Remove(ctx context.Context, id string) error
+	Len() int
+	Close() error
+}
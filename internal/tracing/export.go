@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// NewExporter crea el exporter indicado por kind: "stdout", "file" (JSON en path) u
+// "otlp" (OTLP/HTTP en endpoint, ej: http://otel-collector:4318). "none" o vacío retorna
+// un exporter nil.
+func NewExporter(ctx context.Context, kind, path, endpoint string) (sdktrace.SpanExporter, error) {
+	switch kind {
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "file":
+		return NewFileExporter(path)
+	case "otlp":
+		return otlptracehttp.New(ctx,
+			otlptracehttp.WithEndpointURL(strings.TrimSuffix(endpoint, "/")+"/v1/traces"))
+	case "", "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("exporter de trazas desconocido %q", kind)
+	}
+}
+
+// fileExporter cierra el archivo al apagar el exporter de stdouttrace que escribe en él
+type fileExporter struct {
+	*stdouttrace.Exporter
+	f *os.File
+}
+
+// NewFileExporter agrega los spans como JSON al archivo indicado
+func NewFileExporter(path string) (sdktrace.SpanExporter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("crear directorio de trazas: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("abrir archivo de trazas: %w", err)
+	}
+	exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &fileExporter{Exporter: exp, f: f}, nil
+}
+
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	err := e.Exporter.Shutdown(ctx)
+	if cerr := e.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,38 @@
+// Package tracing configura las trazas distribuidas con el SDK de OpenTelemetry. Los spans
+// se propagan en el context.Context y entre servicios en los headers W3C Trace Context
+// (traceparent/tracestate) de Kafka.
+//
+// Mientras no se configure un exporter los spans no se exportan, pero los identificadores
+// se siguen propagando para no cortar la traza de los servicios vecinos.
+package tracing
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Configure instala el propagador W3C Trace Context y el TracerProvider global. ratio es
+// la fracción de trazas nuevas que se muestrean; las que llegan de otro servicio respetan
+// su flag sampled. Con exporter nil no se muestrean trazas nuevas.
+//
+// El llamador debe invocar Shutdown del provider al terminar para exportar los spans
+// pendientes.
+func Configure(service string, ratio float64, exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	root := sdktrace.NeverSample()
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", service))),
+	}
+	if exporter != nil {
+		root = sdktrace.TraceIDRatioBased(ratio)
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	opts = append(opts, sdktrace.WithSampler(sdktrace.ParentBased(root)))
+
+	tp := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return tp
+}
@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestConfigureExportsOnShutdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces", "spans.log")
+	exp, err := NewExporter(context.Background(), "file", path, "")
+	if err != nil {
+		t.Fatalf("NewExporter: %v", err)
+	}
+	tp := Configure("orquestador-test", 1, exp)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "process user-events")
+	span.End()
+
+	// El lote queda pendiente hasta el intervalo del batcher: Shutdown lo exporta
+	if err := tp.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("leer trazas: %v", err)
+	}
+	for _, want := range []string{"process user-events", "orquestador-test"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("el archivo de trazas no contiene %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestConfigureWithoutExporter(t *testing.T) {
+	tp := Configure("orquestador-test", 1, nil)
+	defer tp.Shutdown(context.Background())
+	tr := otel.Tracer("test")
+
+	// Las trazas nuevas tienen identificadores para propagar, pero no se muestrean
+	_, root := tr.Start(context.Background(), "root")
+	defer root.End()
+	if sc := root.SpanContext(); !sc.IsValid() || sc.IsSampled() {
+		t.Errorf("span raíz = %v, se esperaba válido y sin muestrear", sc)
+	}
+
+	// Las que llegan de otro servicio conservan su traza y su flag sampled
+	remote := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	_, child := tr.Start(trace.ContextWithRemoteSpanContext(context.Background(), remote), "child")
+	defer child.End()
+	if sc := child.SpanContext(); sc.TraceID() != remote.TraceID() || !sc.IsSampled() {
+		t.Errorf("span hijo = %v, se esperaba la traza %s muestreada", sc, remote.TraceID())
+	}
+}
+
+func TestNewExporterUnknown(t *testing.T) {
+	if _, err := NewExporter(context.Background(), "zipkin", "", ""); err == nil {
+		t.Error("se esperaba error para un exporter desconocido")
+	}
+	if exp, err := NewExporter(context.Background(), "none", "", ""); exp != nil || err != nil {
+		t.Errorf("NewExporter(none) = %v, %v; se esperaba nil, nil", exp, err)
+	}
+}